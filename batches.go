@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+	"github.com/OzkrOssa/mekano-cli/repository"
+)
+
+const batchesUsage = `Uso: mekano-cli batches <comando> [opciones] <id>
+
+Comandos:
+  show <id>                     Muestra los datos y las líneas de un lote exportado
+  reexport [-o <archivo>] <id>  Regenera la interfaz idéntica a la del lote, con el
+                                formato guardado en el lote salvo que se indique otro
+`
+
+// runBatches atiende el subcomando batches, que consulta y regenera los lotes
+// exportados a Mekano.
+func runBatches(argv []string) {
+	if len(argv) == 0 {
+		fmt.Print(batchesUsage)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("batches "+argv[0], flag.ExitOnError)
+	output := fs.String("o", "", "Ruta del archivo de interfaz a generar (por defecto el destino de exportación)")
+	configFile := fs.String("c", os.Getenv(config.ConfigEnv), "Archivo de configuración con la sección export")
+	var dsn, journal string
+	var offline bool
+	var exportOpts exportFlagValues
+	exportFlags(fs, &exportOpts)
+	dbFlag(fs, &dsn)
+	offlineFlags(fs, &offline, &journal)
+	fs.Parse(argv[1:])
+
+	if fs.NArg() != 1 {
+		fmt.Print(batchesUsage)
+		os.Exit(1)
+	}
+	id, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		log.Fatalf("id de lote inválido: %q", fs.Arg(0))
+	}
+
+	d, err := openRepository(dsn, offline, journal)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	batch, err := d.GetBatch(ctx, id)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	switch argv[0] {
+	case "show":
+		fmt.Printf("Lote:      %d\n", batch.ID)
+		fmt.Printf("Tipo:      %s\n", batch.Kind)
+		fmt.Printf("Archivo:   %s\n", batch.FileName)
+		fmt.Printf("SHA-256:   %s\n", batch.Checksum)
+		fmt.Printf("Operador:  %s\n", batch.Operator)
+		fmt.Printf("Fecha:     %s\n", batch.CreateAt)
+		fmt.Printf("Formato:   %s\n", batchFormat(batch.Output))
+		fmt.Printf("Líneas:    %d\n\n", len(batch.Lines))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "TIPO\tNÚMERO\tSEC\tFECHA\tCUENTA\tTERCERO\tC. COSTOS\tDÉBITO\tCRÉDITO\tBASE")
+		for _, l := range batch.Lines {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				l.Tipo, l.Numero, l.Secuencia, l.Fecha, l.Cuenta, l.Terceros, l.CentroCostos, l.Debito, l.Credito, l.Base)
+		}
+		w.Flush()
+
+	case "reexport":
+		export, format, err := loadExport(*configFile, exportOpts)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if *output == "" {
+			*output = export.Path()
+		}
+		if batch.Output != nil {
+			format = exportOpts.outputOptions(*batch.Output)
+			if err := format.Validate(); err != nil {
+				log.Fatalln(err)
+			}
+		}
+		if err := repository.WriteBatch(*output, batch, format); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Lote %d regenerado en %s (%d líneas)\n", batch.ID, *output, len(batch.Lines))
+
+	default:
+		fmt.Print(batchesUsage)
+		os.Exit(1)
+	}
+}
+
+// batchFormat describe el formato con que se exportó el lote.
+func batchFormat(output *repository.OutputOptions) string {
+	if output == nil {
+		return "no registrado"
+	}
+	encoding, eol := output.Encoding, "LF"
+	if encoding == "" {
+		encoding = "utf-8"
+	}
+	if output.CRLF {
+		eol = "CRLF"
+	}
+	return encoding + ", " + eol
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/repository"
+)
+
+// runInit atiende el subcomando init, que prepara una base de datos vacía:
+// aplica las migraciones y, si se indica, el primer consecutivo de RC.
+func runInit(argv []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	rcStart := fs.Int("rc-start", 0, "Primer consecutivo RC que se asignará")
+	var dsn, journal string
+	var offline bool
+	dbFlag(fs, &dsn)
+	offlineFlags(fs, &offline, &journal)
+	fs.Parse(argv)
+
+	d, err := openRepository(dsn, offline, journal)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	applied, err := d.MigrateUp(ctx, 0)
+	printMigrations("Aplicada", applied)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *rcStart > 0 {
+		if err := d.SeedConsecutive(ctx, repository.SequenceRC, *rcStart); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("El próximo RC será %d\n", *rcStart)
+	}
+}
+
+// runMigrate atiende el subcomando migrate: up, down y status.
+func runMigrate(argv []string) {
+	if len(argv) == 0 {
+		fmt.Println("Uso: mekano-cli migrate up|down|status [-steps N]")
+		os.Exit(1)
+	}
+
+	action := argv[0]
+	fs := flag.NewFlagSet("migrate "+action, flag.ExitOnError)
+	steps := fs.Int("steps", 0, "Cantidad de migraciones a aplicar o revertir (up: todas por defecto, down: 1)")
+	var dsn string
+	dbFlag(fs, &dsn)
+	fs.Parse(argv[1:])
+
+	d, err := openDatabase(dsn)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch action {
+	case "up":
+		applied, err := d.MigrateUp(ctx, *steps)
+		printMigrations("Aplicada", applied)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("El esquema está al día")
+		}
+	case "down":
+		if *steps <= 0 {
+			*steps = 1
+		}
+		reverted, err := d.MigrateDown(ctx, *steps)
+		printMigrations("Revertida", reverted)
+		if err != nil {
+			log.Fatalln(err)
+		}
+	case "status":
+		status, err := d.MigrationStatus(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, s := range status {
+			state := "pendiente"
+			if s.Applied {
+				state = "aplicada " + s.AppliedAt
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("acción de migrate desconocida: %q (use up, down o status)", action)
+	}
+}
+
+func printMigrations(verb string, migrations []repository.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
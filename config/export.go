@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Variables de entorno con el destino de la interfaz cuando no se indican
+// los flags -export-dir y -export-file.
+const (
+	ExportDirEnv      = "MEKANO_EXPORT_DIR"
+	ExportFileEnv     = "MEKANO_EXPORT_FILE"
+	ExportEncodingEnv = "MEKANO_EXPORT_ENCODING"
+	ExportCRLFEnv     = "MEKANO_EXPORT_CRLF"
+)
+
+// DefaultExportFile es el nombre del archivo de interfaz que importa Mekano.
+const DefaultExportFile = "CONTABLE.txt"
+
+// Export es el destino y el formato del archivo de interfaz. En el archivo
+// de configuración va en la sección export:
+//
+//	export:
+//	  dir: D:/MEKANO/INTERFACES
+//	  file: CONTABLE.txt
+//	  encoding: windows-1252
+//	  crlf: true
+type Export struct {
+	Dir  string `json:"dir" yaml:"dir" toml:"dir"`
+	File string `json:"file" yaml:"file" toml:"file"`
+	// Encoding es la codificación del archivo: utf-8 (por defecto),
+	// utf-8-bom, windows-1252 o iso-8859-1.
+	Encoding string `json:"encoding" yaml:"encoding" toml:"encoding"`
+	// CRLF termina las líneas con \r\n en lugar de \n.
+	CRLF bool `json:"crlf" yaml:"crlf" toml:"crlf"`
+}
+
+// LoadExport lee el destino de la interfaz de la sección export del archivo
+// de configuración, si path no está vacío. Las variables de entorno tienen
+// prioridad sobre el archivo, y lo que falte se completa con MekanoExportPath
+// y DefaultExportFile.
+func LoadExport(path string) (Export, error) {
+	var file struct {
+		Export Export `json:"export" yaml:"export" toml:"export"`
+	}
+	if path != "" {
+		if err := readConfigFile(path, &file); err != nil {
+			return Export{}, err
+		}
+	}
+
+	e := file.Export
+	if dir := os.Getenv(ExportDirEnv); dir != "" {
+		e.Dir = dir
+	}
+	if name := os.Getenv(ExportFileEnv); name != "" {
+		e.File = name
+	}
+	if encoding := os.Getenv(ExportEncodingEnv); encoding != "" {
+		e.Encoding = encoding
+	}
+	if crlf := os.Getenv(ExportCRLFEnv); crlf != "" {
+		v, err := strconv.ParseBool(crlf)
+		if err != nil {
+			return Export{}, fmt.Errorf("%s: valor inválido %q", ExportCRLFEnv, crlf)
+		}
+		e.CRLF = v
+	}
+	if e.Dir == "" {
+		e.Dir = MekanoExportPath
+	}
+	if e.File == "" {
+		e.File = DefaultExportFile
+	}
+	return e, nil
+}
+
+// Path devuelve la ruta completa del archivo de interfaz.
+func (e Export) Path() string {
+	return filepath.Join(e.Dir, e.File)
+}
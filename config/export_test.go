@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadExport(t *testing.T) {
+	e, err := LoadExport("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Path() != filepath.Join(MekanoExportPath, DefaultExportFile) {
+		t.Errorf("Destino por defecto inesperado: %s", e.Path())
+	}
+
+	path := filepath.Join(t.TempDir(), "mekano.yaml")
+	content := "export:\n  dir: D:/MEKANO\n  file: INTERFAZ.txt\naccounts:\n  PLAN SENIOR SIMETRICO: \"41457057\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e, err = LoadExport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != (Export{Dir: "D:/MEKANO", File: "INTERFAZ.txt"}) {
+		t.Errorf("Destino del archivo inesperado: %+v", e)
+	}
+
+	// Las variables de entorno tienen prioridad sobre el archivo.
+	t.Setenv(ExportDirEnv, "E:/INTERFACES")
+	t.Setenv(ExportEncodingEnv, "windows-1252")
+	t.Setenv(ExportCRLFEnv, "true")
+	e, err = LoadExport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != (Export{Dir: "E:/INTERFACES", File: "INTERFAZ.txt", Encoding: "windows-1252", CRLF: true}) {
+		t.Errorf("Destino con variables de entorno inesperado: %+v", e)
+	}
+
+	t.Setenv(ExportCRLFEnv, "tal vez")
+	if _, err := LoadExport(path); err == nil {
+		t.Errorf("Se esperaba un error por %s inválido", ExportCRLFEnv)
+	}
+
+	// El archivo de mapeos sigue siendo válido con la sección export.
+	if _, err := LoadMappings(path); err != nil {
+		t.Errorf("Error al leer los mapeos: %v", err)
+	}
+}
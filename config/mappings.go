@@ -0,0 +1,230 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigEnv es la variable de entorno con la ruta del archivo de mapeos
+// cuando no se indica el flag -c.
+const ConfigEnv = "MEKANO_CONFIG"
+
+// Mappings agrupa las equivalencias entre los valores de los archivos de la
+// plataforma de facturación y las cuentas / centros de costos de Mekano.
+type Mappings struct {
+	Accounts   map[string]string `json:"accounts" yaml:"accounts" toml:"accounts"`
+	Cashier    map[string]string `json:"cashier" yaml:"cashier" toml:"cashier"`
+	CostCenter map[string]string `json:"cost_center" yaml:"cost_center" toml:"cost_center"`
+}
+
+// DefaultMappings devuelve una copia de los mapeos compilados en el binario.
+func DefaultMappings() Mappings {
+	return Mappings{
+		Accounts:   copyMap(Accounts),
+		Cashier:    copyMap(Cashier),
+		CostCenter: copyMap(CostCenter),
+	}
+}
+
+// Secciones de mapeos, usadas como nombre de sección en los archivos de
+// configuración y como tipo de mapeo en la base de datos.
+const (
+	SectionAccounts   = "accounts"
+	SectionCashier    = "cashier"
+	SectionCostCenter = "cost_center"
+)
+
+// Sections lista las secciones de mapeos en el orden en que se muestran.
+var Sections = []string{SectionAccounts, SectionCashier, SectionCostCenter}
+
+// LoadMappings lee los mapeos desde un archivo YAML, JSON o TOML según su
+// extensión. Las secciones ausentes en el archivo se toman de los mapeos
+// compilados. Si path está vacío se devuelven los mapeos compilados.
+func LoadMappings(path string) (Mappings, error) {
+	if path == "" {
+		return DefaultMappings(), nil
+	}
+
+	m, err := ReadMappingsFile(path)
+	if err != nil {
+		return Mappings{}, err
+	}
+
+	defaults := DefaultMappings()
+	if m.Accounts == nil {
+		m.Accounts = defaults.Accounts
+	}
+	if m.Cashier == nil {
+		m.Cashier = defaults.Cashier
+	}
+	if m.CostCenter == nil {
+		m.CostCenter = defaults.CostCenter
+	}
+	return m, nil
+}
+
+// ReadMappingsFile lee y valida un archivo de mapeos sin completar las
+// secciones ausentes.
+func ReadMappingsFile(path string) (Mappings, error) {
+	var m Mappings
+	if err := readConfigFile(path, &m); err != nil {
+		return Mappings{}, err
+	}
+
+	if err := m.Validate(); err != nil {
+		return Mappings{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return m, nil
+}
+
+// readConfigFile decodifica un archivo de configuración YAML, JSON o TOML
+// según su extensión. Las claves que v no conoce se ignoran, de modo que el
+// mismo archivo puede tener los mapeos y otras secciones.
+func readConfigFile(path string, v any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, v)
+	case ".json":
+		err = json.Unmarshal(content, v)
+	case ".toml":
+		err = toml.Unmarshal(content, v)
+	default:
+		return fmt.Errorf("formato de configuración no soportado: %s", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// WriteMappingsFile guarda los mapeos en formato YAML, JSON o TOML según la
+// extensión de path.
+func WriteMappingsFile(path string, m Mappings) error {
+	var content []byte
+	var err error
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		content, err = yaml.Marshal(m)
+	case ".json":
+		content, err = json.MarshalIndent(m, "", "  ")
+	case ".toml":
+		var buf bytes.Buffer
+		err = toml.NewEncoder(&buf).Encode(m)
+		content = buf.Bytes()
+	default:
+		return fmt.Errorf("formato de configuración no soportado: %s", path)
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, content, 0o644)
+}
+
+// Section devuelve el mapa correspondiente a una sección, creándolo si es nil.
+func (m *Mappings) Section(name string) (map[string]string, error) {
+	var section *map[string]string
+	switch name {
+	case SectionAccounts:
+		section = &m.Accounts
+	case SectionCashier:
+		section = &m.Cashier
+	case SectionCostCenter:
+		section = &m.CostCenter
+	default:
+		return nil, fmt.Errorf("sección de mapeos desconocida: %q (use %s)", name, strings.Join(Sections, ", "))
+	}
+	if *section == nil {
+		*section = map[string]string{}
+	}
+	return *section, nil
+}
+
+// Merge devuelve una copia de m con los valores de override aplicados encima.
+func (m Mappings) Merge(override Mappings) Mappings {
+	merged := Mappings{
+		Accounts:   copyMap(m.Accounts),
+		Cashier:    copyMap(m.Cashier),
+		CostCenter: copyMap(m.CostCenter),
+	}
+	for k, v := range override.Accounts {
+		merged.Accounts[k] = v
+	}
+	for k, v := range override.Cashier {
+		merged.Cashier[k] = v
+	}
+	for k, v := range override.CostCenter {
+		merged.CostCenter[k] = v
+	}
+	return merged
+}
+
+// Validate verifica que no haya nombres vacíos y que todas las cuentas y
+// centros de costos sean códigos numéricos.
+func (m Mappings) Validate() error {
+	var problems []string
+
+	check := func(section string, values map[string]string) {
+		for _, key := range SortedKeys(values) {
+			if strings.TrimSpace(key) == "" {
+				problems = append(problems, fmt.Sprintf("%s: nombre vacío", section))
+				continue
+			}
+			if !isNumeric(values[key]) {
+				problems = append(problems, fmt.Sprintf("%s: %q tiene un código inválido %q", section, key, values[key]))
+			}
+		}
+	}
+	check(SectionAccounts, m.Accounts)
+	check(SectionCashier, m.Cashier)
+	check(SectionCostCenter, m.CostCenter)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("mapeos inválidos:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SortedKeys devuelve las llaves de un mapa en orden alfabético.
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultMappingsAreValid(t *testing.T) {
+	if err := DefaultMappings().Validate(); err != nil {
+		t.Fatalf("Los mapeos compilados no son válidos: %v", err)
+	}
+}
+
+func TestLoadMappings(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"mappings.yaml": "accounts:\n  PLAN SENIOR SIMETRICO: \"41457057\"\n",
+		"mappings.json": `{"accounts": {"PLAN SENIOR SIMETRICO": "41457057"}}`,
+		"mappings.toml": "[accounts]\n\"PLAN SENIOR SIMETRICO\" = \"41457057\"\n",
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := LoadMappings(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if m.Accounts["PLAN SENIOR SIMETRICO"] != "41457057" {
+			t.Errorf("%s: cuenta esperada 41457057, obtenida %q", name, m.Accounts["PLAN SENIOR SIMETRICO"])
+		}
+		if m.Cashier["PAYU"] != Cashier["PAYU"] {
+			t.Errorf("%s: las cajas ausentes deben tomarse de los mapeos compilados", name)
+		}
+	}
+}
+
+func TestLoadMappingsInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mappings.yaml")
+	if err := os.WriteFile(path, []byte("cost_center:\n  SUPIA: \"\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := LoadMappings(path); err == nil {
+		t.Errorf("Se esperaba un error por centro de costos vacío")
+	}
+}
+
+func TestWriteMappingsFile(t *testing.T) {
+	m := DefaultMappings().Merge(Mappings{
+		Accounts: map[string]string{"PLAN SENIOR SIMETRICO": "41457057"},
+	})
+
+	for _, name := range []string{"mappings.yaml", "mappings.json", "mappings.toml"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := WriteMappingsFile(path, m); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		read, err := ReadMappingsFile(path)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(read, m) {
+			t.Errorf("%s: los mapeos leídos no coinciden con los escritos", name)
+		}
+	}
+}
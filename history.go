@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+	"github.com/OzkrOssa/mekano-cli/repository"
+)
+
+// runHistory atiende el subcomando history, que lista las ejecuciones de
+// pagos y facturación registradas.
+func runHistory(argv []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	from := fs.String("from", "", "Fecha inicial AAAA-MM-DD")
+	to := fs.String("to", "", "Fecha final AAAA-MM-DD")
+	file := fs.String("file", "", "Parte del nombre del archivo de entrada")
+	kind := fs.String("kind", "", "Solo pagos o facturacion")
+	asJSON := fs.Bool("json", false, "Imprime el historial en JSON")
+	configFile := fs.String("c", os.Getenv(config.ConfigEnv), "Ruta del archivo de mapeos (YAML, JSON o TOML)")
+	var dsn, journal string
+	var offline bool
+	dbFlag(fs, &dsn)
+	offlineFlags(fs, &offline, &journal)
+	fs.Parse(argv)
+
+	for _, date := range []string{*from, *to} {
+		if _, err := time.Parse("2006-01-02", date); date != "" && err != nil {
+			log.Fatalf("fecha inválida %q, use AAAA-MM-DD", date)
+		}
+	}
+	if *kind != "" && *kind != repository.BatchPayment && *kind != repository.BatchBilling {
+		log.Fatalf("tipo inválido %q, use %s o %s", *kind, repository.BatchPayment, repository.BatchBilling)
+	}
+
+	d, err := openRepository(dsn, offline, journal)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	mappings, err := loadMappings(*configFile, d)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	h, err := repository.LoadHistory(ctx, d, mappings, repository.HistoryFilter{From: *from, To: *to, File: *file})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	switch *kind {
+	case repository.BatchPayment:
+		h.Billings = []repository.BillingRun{}
+	case repository.BatchBilling:
+		h.Payments = []repository.PaymentRun{}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", " ")
+		if err := enc.Encode(h); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	if *kind != repository.BatchBilling {
+		fmt.Fprintln(w, "FECHA\tLOTE\tARCHIVO\tRANGO RC\tFILAS\tBANCOLOMBIA\tDAVIVIENDA\tSUSUERTE\tPAYU\tEFECTIVO\tTOTAL\t")
+		for _, p := range h.Payments {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\t%s\t\n",
+				p.Date, batchLabel(p.BatchID), p.FileName, p.RangoRC, p.Filas, p.Bancolombia, p.Davivienda, p.Susuerte, p.PayU, p.Efectivo, p.Total)
+		}
+		fmt.Fprintln(w)
+	}
+	if *kind != repository.BatchPayment {
+		fmt.Fprintln(w, "FECHA\tLOTE\tARCHIVO\tDÉBITO\tCRÉDITO\tBASE\t")
+		for _, b := range h.Billings {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t\n", b.Date, batchLabel(b.BatchID), b.FileName, b.Debito, b.Credito, b.Base)
+		}
+	}
+	w.Flush()
+}
+
+func batchLabel(id int64) string {
+	if id == 0 {
+		return "-"
+	}
+	return fmt.Sprint(id)
+}
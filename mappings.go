@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+const mappingsUsage = `Uso: mekano-cli mappings <comando> [opciones]
+
+Comandos:
+  list                          Lista los mapeos vigentes
+  add -s <sección> <nombre> <código>
+                                Agrega o actualiza un mapeo en la base de datos
+  remove -s <sección> <nombre>  Elimina un mapeo de la base de datos
+  import <archivo>              Carga en la base de datos un archivo YAML, JSON o TOML
+  export <archivo>              Guarda los mapeos vigentes en un archivo YAML, JSON o TOML
+
+Secciones: accounts, cashier, cost_center
+`
+
+// runMappings atiende el subcomando mappings, que permite mantener las
+// equivalencias de cuentas sin recompilar el binario.
+func runMappings(argv []string) {
+	if len(argv) == 0 {
+		fmt.Print(mappingsUsage)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("mappings "+argv[0], flag.ExitOnError)
+	section := fs.String("s", "", "Sección del mapeo (accounts, cashier, cost_center)")
+	configFile := fs.String("c", os.Getenv(config.ConfigEnv), "Ruta del archivo de mapeos (YAML, JSON o TOML)")
+	var dsn string
+	dbFlag(fs, &dsn)
+	fs.Parse(argv[1:])
+
+	d, err := openDatabase(dsn)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	switch argv[0] {
+	case "list":
+		mappings, err := loadMappings(*configFile, d)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		stored, err := d.GetMappings(ctx)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SECCIÓN\tNOMBRE\tCÓDIGO\tORIGEN")
+		for _, s := range config.Sections {
+			if *section != "" && *section != s {
+				continue
+			}
+			values, _ := mappings.Section(s)
+			storedValues, _ := stored.Section(s)
+			for _, name := range config.SortedKeys(values) {
+				origin := "config"
+				if _, ok := storedValues[name]; ok {
+					origin = "db"
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s, name, values[name], origin)
+			}
+		}
+		w.Flush()
+
+	case "add":
+		if fs.NArg() != 2 || *section == "" {
+			fmt.Print(mappingsUsage)
+			os.Exit(1)
+		}
+		var m config.Mappings
+		values, err := m.Section(*section)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		values[fs.Arg(0)] = fs.Arg(1)
+		if err := m.Validate(); err != nil {
+			log.Fatalln(err)
+		}
+		if err := d.SaveMappings(ctx, m); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Mapeo guardado: %s %q -> %s\n", *section, fs.Arg(0), fs.Arg(1))
+
+	case "remove":
+		if fs.NArg() != 1 || *section == "" {
+			fmt.Print(mappingsUsage)
+			os.Exit(1)
+		}
+		if err := d.DeleteMapping(ctx, *section, fs.Arg(0)); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Mapeo eliminado: %s %q\n", *section, fs.Arg(0))
+
+	case "import":
+		if fs.NArg() != 1 {
+			fmt.Print(mappingsUsage)
+			os.Exit(1)
+		}
+		m, err := config.ReadMappingsFile(fs.Arg(0))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := d.SaveMappings(ctx, m); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Mapeos importados: %d cuentas, %d cajas, %d centros de costos\n", len(m.Accounts), len(m.Cashier), len(m.CostCenter))
+
+	case "export":
+		if fs.NArg() != 1 {
+			fmt.Print(mappingsUsage)
+			os.Exit(1)
+		}
+		mappings, err := loadMappings(*configFile, d)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		if err := config.WriteMappingsFile(fs.Arg(0), mappings); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Mapeos exportados a %s\n", fs.Arg(0))
+
+	default:
+		fmt.Print(mappingsUsage)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+	"github.com/OzkrOssa/mekano-cli/repository"
+)
+
+const reexportUsage = `Uso: mekano-cli reexport [opciones] (-rc <número> | -file <nombre>)
+
+Regenera la interfaz de una ejecución registrada con sus consecutivos, fechas
+y valores originales, sin modificar la secuencia ni el historial. Si ya conoce
+el lote, use mekano-cli batches reexport <id>.
+
+Las ejecuciones sin lote se reconstruyen desde el archivo de pagos original
+(-p) y pueden no quedar idénticas a la interfaz de entonces: las cuentas salen
+de los mapeos actuales, los montos se escriben con dos decimales redondeados
+según -rounding y la hora de exportación, que no quedó registrada, se
+reemplaza por las 00:00 del día de la ejecución.
+
+Opciones:
+`
+
+// runReexport atiende el subcomando reexport. Las ejecuciones con lote se
+// regeneran desde las líneas guardadas; las anteriores a los lotes, desde el
+// archivo de pagos original indicado con -p.
+func runReexport(argv []string) {
+	fs := flag.NewFlagSet("reexport", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), reexportUsage)
+		fs.PrintDefaults()
+	}
+
+	var q repository.RunQuery
+	fs.IntVar(&q.RC, "rc", 0, "Un RC asignado por la ejecución de pagos")
+	fs.StringVar(&q.File, "file", "", "Parte del nombre del archivo de entrada de la ejecución")
+	paymentFile := fs.String("p", "", "Archivo de pagos original, para ejecuciones sin lote")
+	rounding := fs.String("rounding", os.Getenv(repository.RoundingEnv), "Redondeo de los montos de las ejecuciones sin lote: peso, half-up o half-even (por defecto "+repository.RoundingEnv+" o peso)")
+	output := fs.String("o", "", "Ruta del archivo de interfaz a generar (por defecto el destino de exportación)")
+	configFile := fs.String("c", os.Getenv(config.ConfigEnv), "Ruta del archivo de mapeos (YAML, JSON o TOML)")
+	var dsn, journal string
+	var offline bool
+	var exportOpts exportFlagValues
+	exportFlags(fs, &exportOpts)
+	dbFlag(fs, &dsn)
+	offlineFlags(fs, &offline, &journal)
+	fs.Parse(argv)
+
+	if fs.NArg() != 0 || (q.RC == 0 && q.File == "") {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	policy, err := repository.ParseRoundingPolicy(*rounding)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	d, err := openRepository(dsn, offline, journal)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	batch, err := repository.FindRun(ctx, d, q)
+	var unbatched *repository.UnbatchedRunError
+	switch {
+	case errors.As(err, &unbatched) && *paymentFile != "":
+		mappings, err := loadMappings(*configFile, d)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		mekano := repository.NewMekanoRepository(d, mappings, repository.Options{Rounding: policy})
+		run := unbatched.Payment
+		lines, err := mekano.RebuildPayment(*paymentFile, run)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		batch = repository.Batch{Kind: repository.BatchPayment, FileName: run.FileName, CreateAt: run.CreateAt, Lines: lines}
+	case err != nil:
+		log.Fatalln(err)
+	}
+
+	export, format, err := loadExport(*configFile, exportOpts)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if *output == "" {
+		*output = export.Path()
+	}
+	if err := repository.WriteBatch(*output, batch, format); err != nil {
+		log.Fatalln(err)
+	}
+	if batch.ID != 0 {
+		fmt.Printf("Lote %d (%s %s del %s) regenerado en %s (%d líneas)\n", batch.ID, batch.Kind, batch.FileName, batch.CreateAt, *output, len(batch.Lines))
+	} else {
+		fmt.Printf("Ejecución de %s %s del %s reconstruida en %s (%d líneas)\n", batch.Kind, batch.FileName, batch.CreateAt, *output, len(batch.Lines))
+	}
+}
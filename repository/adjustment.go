@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+)
+
+// Variables de entorno del ajuste al peso cuando no se indican los flags
+// -rounding-account y -rounding-tolerance.
+const (
+	AdjustmentAccountEnv   = "MEKANO_ROUNDING_ACCOUNT"
+	AdjustmentToleranceEnv = "MEKANO_ROUNDING_TOLERANCE"
+)
+
+// DefaultAdjustmentTolerance es la diferencia máxima que se ajusta si no se
+// indica otra: un peso.
+const DefaultAdjustmentTolerance Money = 1_00
+
+// AdjustmentOptions configura el ajuste al peso de las facturas. Como base,
+// IVA y total se redondean por separado, un comprobante puede quedar
+// descuadrado por unos centavos o un peso.
+type AdjustmentOptions struct {
+	// Account es la cuenta donde se registra el ajuste. Si está vacía no se
+	// generan ajustes y esos comprobantes se rechazan como descuadrados.
+	Account string
+	// Tolerance es la diferencia máxima que se ajusta; los comprobantes con
+	// una diferencia mayor se rechazan.
+	Tolerance Money
+}
+
+// adjustRounding agrega a cada comprobante descuadrado dentro de la
+// tolerancia una línea a la cuenta de ajuste por la diferencia, justo después
+// de sus líneas. La línea toma los datos del tercero de la última línea del
+// comprobante. Los comprobantes que superan la tolerancia no se tocan, para
+// que la validación de cuadre los rechace.
+func adjustRounding(data []MekanoDataStruct, opts AdjustmentOptions) ([]MekanoDataStruct, int) {
+	if opts.Account == "" || len(data) == 0 {
+		return data, 0
+	}
+
+	var adjusted []MekanoDataStruct
+	var count int
+	var total Money
+	var difference Money
+
+	for i, d := range data {
+		adjusted = append(adjusted, d)
+		difference += lineAmount(d.Debito) - lineAmount(d.Credito)
+
+		if i+1 < len(data) && voucherKey(data[i+1]) == voucherKey(d) {
+			continue
+		}
+		if abs(difference) > opts.Tolerance {
+			log.Printf("Comprobante %s descuadrado por %s, supera la tolerancia de ajuste de %s", voucherKey(d), difference, opts.Tolerance)
+		} else if difference != 0 {
+			line := d
+			line.Cuenta = opts.Account
+			line.Nota = "AJUSTE AL PESO"
+			line.Debito, line.Credito, line.Base = Money(0).String(), Money(0).String(), Money(0).String()
+			if difference > 0 {
+				line.Credito = difference.String()
+			} else {
+				line.Debito = (-difference).String()
+			}
+			adjusted = append(adjusted, line)
+			count++
+			total += difference
+		}
+		difference = 0
+	}
+
+	if count > 0 {
+		log.Printf("Ajuste al peso en %d comprobantes a la cuenta %s, neto %s", count, opts.Account, total)
+	}
+	return adjusted, count
+}
+
+// ParseAdjustmentTolerance lee la tolerancia del ajuste al peso. Si está
+// vacía se usa DefaultAdjustmentTolerance.
+func ParseAdjustmentTolerance(s string) (Money, error) {
+	if s == "" {
+		return DefaultAdjustmentTolerance, nil
+	}
+	m, err := ParseMoney(s, RoundHalfEven)
+	if err != nil || m <= 0 {
+		return 0, fmt.Errorf("tolerancia de ajuste inválida: %q, debe ser mayor que cero", s)
+	}
+	return m, nil
+}
+
+func abs(m Money) Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestAdjustRounding(t *testing.T) {
+	data := []MekanoDataStruct{
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Cuenta: "41457070", Debito: "0.00", Credito: "63950.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Cuenta: "24080505", Debito: "0.00", Credito: "16525.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Cuenta: "13050501", Debito: "80476.00", Credito: "0.00", Terceros: "159122542"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66138", Cuenta: "41457070", Debito: "0.00", Credito: "63025.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66138", Cuenta: "13050501", Debito: "63024.79", Credito: "0.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66139", Cuenta: "41457070", Debito: "0.00", Credito: "1000.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66139", Cuenta: "13050501", Debito: "1000.00", Credito: "0.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66140", Cuenta: "41457070", Debito: "0.00", Credito: "1000.00"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66140", Cuenta: "13050501", Debito: "1005.00", Credito: "0.00"},
+	}
+
+	if adjusted, n := adjustRounding(data, AdjustmentOptions{}); n != 0 || !reflect.DeepEqual(adjusted, data) {
+		t.Fatalf("Sin cuenta de ajuste no se esperaban cambios, se obtuvieron %d ajustes", n)
+	}
+
+	adjusted, n := adjustRounding(data, AdjustmentOptions{Account: "42581001", Tolerance: DefaultAdjustmentTolerance})
+	if n != 2 || len(adjusted) != len(data)+2 {
+		t.Fatalf("Se esperaban 2 ajustes, se obtuvieron %d: %+v", n, adjusted)
+	}
+
+	expected := MekanoDataStruct{Tipo: "FVE", Prefijo: "_", Numero: "66137", Cuenta: "42581001", Nota: "AJUSTE AL PESO", Debito: "0.00", Credito: "1.00", Base: "0.00", Terceros: "159122542"}
+	if adjusted[3] != expected {
+		t.Errorf("Ajuste inesperado:\nEsperado: %+v\nObtenido: %+v", expected, adjusted[3])
+	}
+	if adjusted[6].Cuenta != "42581001" || adjusted[6].Debito != "0.21" || adjusted[6].Credito != "0.00" {
+		t.Errorf("Ajuste inesperado: %+v", adjusted[6])
+	}
+
+	// El comprobante que supera la tolerancia queda descuadrado.
+	err := checkBalance(adjusted, map[string]int{})
+	unbalanced, ok := err.(*UnbalancedError)
+	if !ok || len(unbalanced.Vouchers) != 1 || unbalanced.Vouchers[0].Voucher != "FVE _ 66140" {
+		t.Errorf("Se esperaba solo FVE _ 66140 descuadrado, se obtuvo: %v", err)
+	}
+}
+
+func TestMekanoBillingRoundingAdjustment(t *testing.T) {
+	dr := &fakeDatabaseRepository{}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{
+		ExportPath: testExportPath(t),
+		Rounding:   RoundHalfEven,
+		Adjustment: AdjustmentOptions{Account: "42581001"},
+	})
+
+	data, err := mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturación: %v", err)
+	}
+
+	var adjustments int
+	for _, d := range data {
+		if d.Cuenta == "42581001" {
+			adjustments++
+		}
+	}
+	if adjustments == 0 {
+		t.Error("Se esperaba al menos una línea de ajuste al peso")
+	}
+	if err := checkBalance(data, map[string]int{}); err != nil {
+		t.Errorf("La interfaz ajustada debe cuadrar: %v", err)
+	}
+}
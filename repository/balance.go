@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// VoucherImbalance describe un comprobante cuyos débitos no coinciden con
+// sus créditos.
+type VoucherImbalance struct {
+	Voucher string
+	Row     int
+	Debit   Money
+	Credit  Money
+}
+
+// Difference devuelve débitos menos créditos.
+func (v VoucherImbalance) Difference() Money {
+	return v.Debit - v.Credit
+}
+
+// UnbalancedError se devuelve cuando uno o más comprobantes no cuadran y por
+// lo tanto no se exporta la interfaz.
+type UnbalancedError struct {
+	Vouchers []VoucherImbalance
+}
+
+func (e *UnbalancedError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d comprobantes descuadrados, no se exportó la interfaz:", len(e.Vouchers))
+	for _, v := range e.Vouchers {
+		fmt.Fprintf(&sb, "\n  %s (fila %d): débito %s, crédito %s, diferencia %s", v.Voucher, v.Row, v.Debit, v.Credit, v.Difference())
+	}
+	return sb.String()
+}
+
+// voucherKey identifica un comprobante por Tipo, Prefijo y Número.
+func voucherKey(d MekanoDataStruct) string {
+	return d.Tipo + " " + d.Prefijo + " " + d.Numero
+}
+
+// checkBalance agrupa las líneas por comprobante y verifica que los débitos
+// sean iguales a los créditos. sources relaciona cada comprobante con la fila
+// del archivo de origen que lo generó.
+func checkBalance(data []MekanoDataStruct, sources map[string]int) error {
+	var order []string
+	debits := map[string]Money{}
+	credits := map[string]Money{}
+
+	for _, d := range data {
+		key := voucherKey(d)
+		if _, ok := debits[key]; !ok {
+			order = append(order, key)
+		}
+		debits[key] += lineAmount(d.Debito)
+		credits[key] += lineAmount(d.Credito)
+	}
+
+	var unbalanced []VoucherImbalance
+	for _, key := range order {
+		if debits[key] != credits[key] {
+			unbalanced = append(unbalanced, VoucherImbalance{
+				Voucher: key,
+				Row:     sources[key],
+				Debit:   debits[key],
+				Credit:  credits[key],
+			})
+		}
+	}
+
+	if len(unbalanced) > 0 {
+		return &UnbalancedError{Vouchers: unbalanced}
+	}
+	return nil
+}
+
+// writeRejectionReport guarda los comprobantes descuadrados en un CSV.
+func writeRejectionReport(path string, e *UnbalancedError) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"comprobante", "fila", "debito", "credito", "diferencia"})
+	for _, v := range e.Vouchers {
+		writer.Write([]string{
+			v.Voucher,
+			strconv.Itoa(v.Row),
+			v.Debit.String(),
+			v.Credit.String(),
+			v.Difference().String(),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
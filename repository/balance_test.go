@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckBalance(t *testing.T) {
+	data := []MekanoDataStruct{
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Debito: "0", Credito: "63950.000000"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Debito: "0", Credito: "16525.000000"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66137", Debito: "80475.000000", Credito: "0"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66138", Debito: "0", Credito: "63025.000000"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66138", Debito: "0", Credito: "11974.000000"},
+		{Tipo: "FVE", Prefijo: "_", Numero: "66138", Debito: "75000.000000", Credito: "0"},
+	}
+	sources := map[string]int{"FVE _ 66137": 2, "FVE _ 66138": 3}
+
+	err := checkBalance(data, sources)
+
+	var unbalanced *UnbalancedError
+	if !errors.As(err, &unbalanced) {
+		t.Fatalf("Se esperaba un UnbalancedError, se obtuvo: %v", err)
+	}
+	if len(unbalanced.Vouchers) != 1 {
+		t.Fatalf("Se esperaba 1 comprobante descuadrado, se obtuvieron %d", len(unbalanced.Vouchers))
+	}
+
+	v := unbalanced.Vouchers[0]
+	if v.Voucher != "FVE _ 66138" || v.Row != 3 || v.Difference() != 1_00 {
+		t.Errorf("Comprobante descuadrado inesperado: %+v", v)
+	}
+
+	if err := checkBalance(data[:3], sources); err != nil {
+		t.Errorf("No se esperaba error para un comprobante cuadrado: %v", err)
+	}
+}
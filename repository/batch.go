@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/user"
+	"time"
+)
+
+// WriteBatch escribe las líneas de un lote en path, idénticas a como se
+// exportaron originalmente si opts es el formato de esa exportación. Como en
+// cada exportación, el archivo anterior se conserva como respaldo.
+func WriteBatch(path string, batch Batch, opts OutputOptions) error {
+	return exporterFile(path, batch.Lines, opts)
+}
+
+// DefaultOperator devuelve el usuario del sistema operativo, que se registra
+// como operador de los lotes cuando no se indica otro.
+func DefaultOperator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return ""
+}
+
+// checksum devuelve el SHA-256 en hexadecimal del contenido de los archivos,
+// en el orden recibido.
+func checksum(files ...string) (string, error) {
+	h := sha256.New()
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// saveBatch registra las líneas exportadas como un lote y devuelve su id. El
+// checksum se calcula sobre los archivos de entrada, para saber después si el
+// archivo que se tiene a mano es el mismo que generó el lote. El formato de
+// salida se guarda para que batches reexport regenere el mismo archivo.
+func (mr *mekanoRepository) saveBatch(ctx context.Context, kind string, data []MekanoDataStruct, files ...string) (int64, error) {
+	sum, err := checksum(files...)
+	if err != nil {
+		log.Println(err)
+	}
+
+	output := mr.opts.Output
+	id, err := mr.dr.SaveBatch(ctx, Batch{
+		Kind:     kind,
+		FileName: files[0],
+		Checksum: sum,
+		Operator: mr.opts.Operator,
+		CreateAt: time.Now().Format("2006-01-02 15:04:05"),
+		Output:   &output,
+		Lines:    data,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("la interfaz se exportó pero no se guardó su lote: %w", err)
+	}
+	log.Printf("Lote %d guardado con %d líneas", id, len(data))
+	return id, nil
+}
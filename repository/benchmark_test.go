@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+// writeBenchmarkFiles genera un archivo de facturación con n filas, donde una
+// de cada diez facturas tiene varios ítems, y su archivo de extras.
+func writeBenchmarkFiles(b *testing.B, dir string, n int) (string, string) {
+	b.Helper()
+
+	billingHeader := []interface{}{
+		"Nro Abonado", "Identificación", "Cliente", "Estatus", "Fecha Contrato", "Franquicia", "Tipo Documento", "Prefijo",
+		"Consecutivo", "Fecha Emisión", "Fecha Vencimiento", "Periodo", "Monto Base", "Monto IVA", "Monto Total", "URL PDF",
+		"Cufe", "Municipio", "Zona", "Tipo Servicio", "Detalle Suscripción", "Item Facturado",
+	}
+	extrasHeader := []interface{}{"ABONADO", "ITEM", "BASE", "IVA", "TOTAL"}
+
+	billing := excelize.NewFile()
+	bw, err := billing.NewStreamWriter("Sheet1")
+	if err != nil {
+		b.Fatal(err)
+	}
+	extras := excelize.NewFile()
+	ew, err := extras.NewStreamWriter("Sheet1")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	if err := bw.SetRow("A1", billingHeader); err != nil {
+		b.Fatal(err)
+	}
+	if err := ew.SetRow("A1", extrasHeader); err != nil {
+		b.Fatal(err)
+	}
+
+	extrasRow := 2
+	for i := 0; i < n; i++ {
+		abonado := strconv.Itoa(1000 + i)
+		item := "F.O. COMERCIAL BASICO."
+		base, iva, total := "63025.21", "11974.79", "75000.00"
+
+		if i%10 == 0 {
+			item = "F.O. COMERCIAL BASICO., IP PUBLICA"
+			base, iva, total = "86974.79", "16525.21", "103500.00"
+			for _, extra := range [][]interface{}{
+				{abonado, "F.O. COMERCIAL BASICO.", "63950", "12150", "76100"},
+				{abonado, "IP PUBLICA", "23025", "4375", "27400"},
+			} {
+				cell, _ := excelize.CoordinatesToCellName(1, extrasRow)
+				if err := ew.SetRow(cell, extra); err != nil {
+					b.Fatal(err)
+				}
+				extrasRow++
+			}
+		}
+
+		row := []interface{}{
+			abonado, "1000" + abonado, "CLIENTE " + abonado, "ACTIVO", "09/02/2021", "RED PLANET", "FACTURA", "",
+			strconv.Itoa(60000 + i), "27/06/2023", "05/07/2023", "06/2023", base, iva, total, "",
+			fmt.Sprintf("cufe%d", i), "SUPIA", "SUPIA", "INTERNET", item, item,
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		if err := bw.SetRow(cell, row); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	if err := ew.Flush(); err != nil {
+		b.Fatal(err)
+	}
+
+	billingPath := filepath.Join(dir, "billing.xlsx")
+	extrasPath := filepath.Join(dir, "extras.xlsx")
+	if err := billing.SaveAs(billingPath); err != nil {
+		b.Fatal(err)
+	}
+	if err := extras.SaveAs(extrasPath); err != nil {
+		b.Fatal(err)
+	}
+	return billingPath, extrasPath
+}
+
+func BenchmarkBuildBilling50k(b *testing.B) {
+	billingPath, extrasPath := writeBenchmarkFiles(b, b.TempDir(), 50000)
+	mr := &mekanoRepository{dr: &fakeDatabaseRepository{}, m: config.DefaultMappings()}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := mr.buildBilling(billingPath, extrasPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkBuildBilling50kLegacy mide sobre los mismos archivos la lectura
+// anterior a buildBilling: ambos archivos completos en memoria con GetRows y
+// una búsqueda lineal en los extras por cada ítem de las facturas con varios
+// ítems. Sirve de referencia para comparar con BenchmarkBuildBilling50k:
+//
+//	go test ./repository -run '^$' -bench 'BuildBilling50k' -benchtime 3x -count 5 > bench.txt
+//	benchstat -col /name bench.txt
+func BenchmarkBuildBilling50kLegacy(b *testing.B) {
+	billingPath, extrasPath := writeBenchmarkFiles(b, b.TempDir(), 50000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := legacyBuildBilling(billingPath, extrasPath); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// legacyBuildBilling reproduce cómo se leían los archivos antes del
+// recorrido por filas y del índice de extras, con las mismas líneas de
+// ingreso, IVA y cuenta por cobrar por factura.
+func legacyBuildBilling(billingPath, extrasPath string) ([]MekanoDataStruct, error) {
+	billingRecords, err := legacyReadSheet(billingSchema, billingPath)
+	if err != nil {
+		return nil, err
+	}
+	extrasRecords, err := legacyReadSheet(extrasSchema, extrasPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []MekanoDataStruct
+	for _, bRow := range billingRecords {
+		line := func(cuenta, debito, credito, base string) MekanoDataStruct {
+			return MekanoDataStruct{
+				Tipo:          "FVE",
+				Prefijo:       "_",
+				Numero:        bRow.get(colFacturaConsecutivo),
+				Fecha:         bRow.get(colFacturaFecha),
+				Cuenta:        cuenta,
+				Terceros:      bRow.get(colFacturaIdentificacion),
+				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+				Debito:        debito,
+				Credito:       credito,
+				Base:          base,
+				Usuario:       "SUPERVISOR",
+				NombreTercero: bRow.get(colFacturaCliente),
+				NombreCentro:  bRow.get(colFacturaMunicipio),
+				Interface:     time.Now().Format("02/01/2006 15:04"),
+			}
+		}
+		base, _ := strconv.ParseFloat(bRow.get(colFacturaBase), 64)
+		iva, _ := strconv.ParseFloat(bRow.get(colFacturaIva), 64)
+		total, _ := strconv.ParseFloat(bRow.get(colFacturaTotal), 64)
+
+		if !strings.Contains(bRow.get(colFacturaItem), ",") {
+			data = append(data, line(bRow.get(colFacturaItem), "0", fmt.Sprintf("%f", base), "0"))
+		} else {
+			for _, item := range strings.Split(bRow.get(colFacturaItem), ",") {
+				for _, extra := range extrasRecords {
+					if extra.get(colExtraItem) == strings.TrimSpace(item) && extra.get(colExtraAbonado) == bRow.get(colFacturaAbonado) {
+						itemBase, _ := strconv.ParseFloat(extra.get(colExtraBase), 64)
+						data = append(data, line(strings.TrimSpace(item), "0", fmt.Sprintf("%f", itemBase), "0"))
+					}
+				}
+			}
+		}
+		data = append(data, line("24080505", "0", fmt.Sprintf("%f", iva), fmt.Sprintf("%f", base)))
+		data = append(data, line("13050505", fmt.Sprintf("%f", total), "0", "0"))
+	}
+	return data, nil
+}
+
+func legacyReadSheet(schema sheetSchema, path string) ([]record, error) {
+	xlsx, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+	defer xlsx.Close()
+
+	rows, err := xlsx.GetRows(xlsx.GetSheetName(0))
+	if err != nil {
+		return nil, err
+	}
+	columns, err := resolveColumns(schema, path, rows[0])
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]record, 0, len(rows)-1)
+	for i, values := range rows[1:] {
+		records = append(records, record{values: values, columns: columns, line: i + 2})
+	}
+	return records, nil
+}
@@ -3,33 +3,183 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/OzkrOssa/mekano-cli/config"
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// Payment es una ejecución de pagos. Consecutive es el último RC asignado.
 type Payment struct {
 	Consecutive int
 	CreateAt    string
 	FileName    string
+	// FirstRC es el primer RC de la ejecución. En las ejecuciones registradas
+	// antes de guardarlo, ListPayments lo deduce de la ejecución anterior.
+	FirstRC int
+	// BatchID es el lote con las líneas exportadas, o 0 si no se guardó.
+	BatchID int64
+	// Rows es la cantidad de recibos de la ejecución.
+	Rows int
+	// Totales recaudados por canal.
+	Bancolombia Money
+	Davivienda  Money
+	Susuerte    Money
+	PayU        Money
+	Cash        Money
+	Total       Money
+}
+
+// paymentColumns son las columnas de mekanopayments en el orden de
+// paymentFields.
+const paymentColumns = "consecutive, create_at, file_name, batch_id, rc_start, rows_count, bancolombia, davivienda, susuerte, payu, cash, total"
+
+// paymentFields devuelve los valores de un pago para insertarlo.
+func paymentFields(p Payment) []interface{} {
+	return []interface{}{p.Consecutive, p.CreateAt, p.FileName, nullID(p.BatchID), p.FirstRC, p.Rows, p.Bancolombia, p.Davivienda, p.Susuerte, p.PayU, p.Cash, p.Total}
+}
+
+// scanPayment lee un pago con las columnas de paymentColumns más las extra.
+func scanPayment(scanner interface{ Scan(...interface{}) error }, extra ...interface{}) (Payment, error) {
+	var p Payment
+	var batchID sql.NullInt64
+	dest := []interface{}{&p.Consecutive, &p.CreateAt, &p.FileName, &batchID, &p.FirstRC, &p.Rows, &p.Bancolombia, &p.Davivienda, &p.Susuerte, &p.PayU, &p.Cash, &p.Total}
+	if err := scanner.Scan(append(dest, extra...)...); err != nil {
+		return Payment{}, err
+	}
+	p.BatchID = batchID.Int64
+	return p, nil
 }
 
 type Billing struct {
-	Debit    int
-	Credit   int
-	Base     int
+	Debit    Money
+	Credit   Money
+	Base     Money
 	CreateAt string
 	FileName string
+	BatchID  int64
+}
+
+// HistoryFilter restringe las ejecuciones que devuelven ListPayments y
+// ListBillings. Los campos vacíos no filtran.
+type HistoryFilter struct {
+	// From y To son fechas AAAA-MM-DD, inclusive.
+	From string
+	To   string
+	// File es parte del nombre del archivo de entrada.
+	File string
+}
+
+// Receipt es un recibo de pago ya importado, con el RC que se le asignó.
+type Receipt struct {
+	Number      string
+	Subscriber  string
+	Amount      string
+	PaymentDate string
+	Consecutive int
+	CreateAt    string
+}
+
+// Invoice es una factura electrónica ya exportada, identificada por su CUFE.
+type Invoice struct {
+	Cufe       string
+	Number     string
+	Subscriber string
+	FileName   string
+	CreateAt   string
 }
 
+// Batch es un lote exportado a Mekano: las líneas exactas de la interfaz y
+// los datos del archivo de entrada que las generó.
+type Batch struct {
+	ID       int64
+	Kind     string
+	FileName string
+	Checksum string
+	Operator string
+	CreateAt string
+	// Output es el formato con que se exportó la interfaz; nil en los lotes
+	// guardados antes de registrarlo.
+	Output *OutputOptions
+	Lines  []MekanoDataStruct
+}
+
+// Tipos de lote.
+const (
+	BatchPayment = "pagos"
+	BatchBilling = "facturacion"
+)
+
 type DatabaseRepositoryInterface interface {
 	GetPayment(ctx context.Context) (Payment, error)
 	SavePayment(ctx context.Context, payment Payment) error
 	SaveBilling(ctx context.Context, billing Billing) error
+	ListPayments(ctx context.Context, filter HistoryFilter) ([]Payment, error)
+	ListBillings(ctx context.Context, filter HistoryFilter) ([]Billing, error)
+	GetMappings(ctx context.Context) (config.Mappings, error)
+	SaveMappings(ctx context.Context, mappings config.Mappings) error
+	DeleteMapping(ctx context.Context, section, name string) error
+	GetReceipts(ctx context.Context, numbers []string) (map[string]Receipt, error)
+	SaveReceipts(ctx context.Context, receipts []Receipt) error
+	GetInvoices(ctx context.Context, cufes []string) (map[string]Invoice, error)
+	SaveInvoices(ctx context.Context, invoices []Invoice) error
+	GetConsecutive(ctx context.Context, sequence string) (int, error)
+	ReserveConsecutives(ctx context.Context, sequence string, n int) (*Reservation, error)
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
+	MigrateUp(ctx context.Context, steps int) ([]Migration, error)
+	MigrateDown(ctx context.Context, steps int) ([]Migration, error)
+	SeedConsecutive(ctx context.Context, sequence string, first int) error
+	SaveBatch(ctx context.Context, batch Batch) (int64, error)
+	GetBatch(ctx context.Context, id int64) (Batch, error)
+}
+
+// ErrNoPaymentHistory indica que la base de datos no tiene pagos registrados
+// ni una secuencia de RC inicializada.
+var ErrNoPaymentHistory = errors.New("no hay historial de pagos: ejecute mekano-cli init -rc-start <primer RC>")
+
+// ErrBatchNotFound indica que no existe el lote pedido.
+var ErrBatchNotFound = errors.New("no existe el lote")
+
+// SequenceRC es la secuencia de consecutivos de los recibos de caja.
+const SequenceRC = "RC"
+
+// Reservation es un rango de consecutivos reservado que todavía no se
+// confirma. Hasta Commit o Rollback ninguna otra ejecución puede reservar en
+// la misma secuencia; Rollback la deja como estaba, sin huecos.
+type Reservation struct {
+	// First es el primer consecutivo del rango.
+	First int
+
+	commit   func() error
+	rollback func() error
+	done     bool
+}
+
+// Commit confirma la reserva.
+func (r *Reservation) Commit() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	return r.commit()
+}
+
+// Rollback descarta la reserva si no se confirmó; después de Commit no hace
+// nada, para poder diferirlo.
+func (r *Reservation) Rollback() error {
+	if r.done {
+		return nil
+	}
+	r.done = true
+	return r.rollback()
 }
 
 type DatabaseRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dialect
 }
 
 func NewDatabaseRepository(dns string) (DatabaseRepositoryInterface, error) {
@@ -47,12 +197,13 @@ func NewDatabaseRepository(dns string) (DatabaseRepositoryInterface, error) {
 	}
 
 	return &DatabaseRepository{
-		db,
+		db:      db,
+		dialect: mysqlDialect,
 	}, nil
 }
 
 func (r *DatabaseRepository) GetPayment(ctx context.Context) (Payment, error) {
-	query := "SELECT consecutive, create_at, file_name FROM mekanopayments ORDER BY id DESC LIMIT 1;"
+	query := "SELECT " + paymentColumns + " FROM mekanopayments ORDER BY id DESC LIMIT 1;"
 	stmt, err := r.db.PrepareContext(ctx, query)
 	if err != nil {
 		return Payment{}, err
@@ -69,10 +220,9 @@ func (r *DatabaseRepository) GetPayment(ctx context.Context) (Payment, error) {
 
 	// Itera sobre los resultados
 	for rows.Next() {
-		var payment Payment
-
 		// Escanea los valores de la fila en la estructura Payment
-		if err := rows.Scan(&payment.Consecutive, &payment.CreateAt, &payment.FileName); err != nil {
+		payment, err := scanPayment(rows)
+		if err != nil {
 			return Payment{}, err
 		}
 
@@ -85,11 +235,15 @@ func (r *DatabaseRepository) GetPayment(ctx context.Context) (Payment, error) {
 		return Payment{}, err
 	}
 
+	if len(payments) == 0 {
+		return Payment{}, ErrNoPaymentHistory
+	}
+
 	return payments[0], nil
 }
 
 func (r *DatabaseRepository) SavePayment(ctx context.Context, payment Payment) error {
-	insertSQL := "INSERT INTO mekanopayments (consecutive, create_at, file_name) VALUES (?, ?, ?)"
+	insertSQL := "INSERT INTO mekanopayments (" + paymentColumns + ") VALUES (?" + strings.Repeat(", ?", len(paymentFields(payment))-1) + ")"
 	stmt, err := r.db.PrepareContext(ctx, insertSQL)
 	if err != nil {
 		return err
@@ -97,7 +251,7 @@ func (r *DatabaseRepository) SavePayment(ctx context.Context, payment Payment) e
 	defer stmt.Close()
 
 	// Ejecuta la consulta con los valores de la estructura Payment
-	_, err = stmt.ExecContext(ctx, payment.Consecutive, payment.CreateAt, payment.FileName)
+	_, err = stmt.ExecContext(ctx, paymentFields(payment)...)
 	if err != nil {
 		return err
 	}
@@ -106,7 +260,7 @@ func (r *DatabaseRepository) SavePayment(ctx context.Context, payment Payment) e
 
 func (r *DatabaseRepository) SaveBilling(ctx context.Context, billing Billing) error {
 	// Implementa la lógica para guardar datos de facturación en la base de datos
-	insertSQL := "INSERT INTO mekanobilling (debit, credit, base, create_at, file_name, ) VALUES (?,?,?,?,?)"
+	insertSQL := "INSERT INTO mekanobilling (debit, credit, base, create_at, file_name, batch_id) VALUES (?, ?, ?, ?, ?, ?)"
 
 	stmt, err := r.db.PrepareContext(ctx, insertSQL)
 
@@ -115,9 +269,483 @@ func (r *DatabaseRepository) SaveBilling(ctx context.Context, billing Billing) e
 	}
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, billing.Debit, billing.Credit, billing.Base, billing.CreateAt, billing.FileName)
+	_, err = stmt.ExecContext(ctx, billing.Debit, billing.Credit, billing.Base, billing.CreateAt, billing.FileName, nullID(billing.BatchID))
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// nullID guarda como NULL los ids sin asignar.
+func nullID(id int64) interface{} {
+	if id == 0 {
+		return nil
+	}
+	return id
+}
+
+// where devuelve las condiciones del filtro sobre la tabla con el alias dado.
+func (f HistoryFilter) where(alias string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if f.From != "" {
+		conditions = append(conditions, alias+".create_at >= ?")
+		args = append(args, f.From)
+	}
+	if f.To != "" {
+		conditions = append(conditions, alias+".create_at <= ?")
+		args = append(args, f.To)
+	}
+	if f.File != "" {
+		conditions = append(conditions, alias+".file_name LIKE ?")
+		args = append(args, "%"+f.File+"%")
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// match indica si una ejecución cumple el filtro, con la misma semántica que
+// where.
+func (f HistoryFilter) match(createAt, fileName string) bool {
+	return (f.From == "" || createAt >= f.From) &&
+		(f.To == "" || createAt <= f.To) &&
+		(f.File == "" || strings.Contains(strings.ToLower(fileName), strings.ToLower(f.File)))
+}
+
+// ListPayments devuelve las ejecuciones de pagos en el orden en que se
+// registraron. Si una ejecución no tiene el primer RC guardado, se deduce del
+// consecutivo de la ejecución anterior, aunque esta no cumpla el filtro.
+func (r *DatabaseRepository) ListPayments(ctx context.Context, filter HistoryFilter) ([]Payment, error) {
+	where, args := filter.where("p")
+	query := "SELECT p." + strings.ReplaceAll(paymentColumns, ", ", ", p.") + ", " +
+		"(SELECT MAX(q.consecutive) FROM mekanopayments q WHERE q.id < p.id) " +
+		"FROM mekanopayments p" + where + " ORDER BY p.id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payments []Payment
+	for rows.Next() {
+		var previous sql.NullInt64
+		payment, err := scanPayment(rows, &previous)
+		if err != nil {
+			return nil, err
+		}
+		if payment.FirstRC == 0 && previous.Valid {
+			payment.FirstRC = int(previous.Int64) + 1
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+// ListBillings devuelve las ejecuciones de facturación en el orden en que se
+// registraron.
+func (r *DatabaseRepository) ListBillings(ctx context.Context, filter HistoryFilter) ([]Billing, error) {
+	where, args := filter.where("b")
+	query := "SELECT b.debit, b.credit, b.base, b.create_at, b.file_name, b.batch_id FROM mekanobilling b" + where + " ORDER BY b.id"
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var billings []Billing
+	for rows.Next() {
+		var billing Billing
+		var batchID sql.NullInt64
+		if err := rows.Scan(&billing.Debit, &billing.Credit, &billing.Base, &billing.CreateAt, &billing.FileName, &batchID); err != nil {
+			return nil, err
+		}
+		billing.BatchID = batchID.Int64
+		billings = append(billings, billing)
+	}
+	return billings, rows.Err()
+}
+
+// GetMappings devuelve los mapeos de planes, cajas y municipios guardados en
+// la tabla mekanomappings.
+func (r *DatabaseRepository) GetMappings(ctx context.Context) (config.Mappings, error) {
+	query := "SELECT section, name, value FROM mekanomappings;"
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return config.Mappings{}, err
+	}
+	defer rows.Close()
+
+	var mappings config.Mappings
+	for rows.Next() {
+		var section, name, value string
+		if err := rows.Scan(&section, &name, &value); err != nil {
+			return config.Mappings{}, err
+		}
+
+		values, err := mappings.Section(section)
+		if err != nil {
+			return config.Mappings{}, err
+		}
+		values[name] = value
+	}
+
+	if err := rows.Err(); err != nil {
+		return config.Mappings{}, err
+	}
+
+	return mappings, nil
+}
+
+// SaveMappings inserta o actualiza en una sola transacción todos los mapeos
+// recibidos.
+func (r *DatabaseRepository) SaveMappings(ctx context.Context, mappings config.Mappings) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, r.dialect.upsertMapping)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, section := range config.Sections {
+		values, err := mappings.Section(section)
+		if err != nil {
+			return err
+		}
+		for name, value := range values {
+			if _, err := stmt.ExecContext(ctx, section, name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *DatabaseRepository) DeleteMapping(ctx context.Context, section, name string) error {
+	deleteSQL := "DELETE FROM mekanomappings WHERE section = ? AND name = ?"
+	result, err := r.db.ExecContext(ctx, deleteSQL, section, name)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return fmt.Errorf("no existe el mapeo %s %q", section, name)
+	}
+	return nil
+}
+
+// inQueryChunk limita la cantidad de valores por consulta IN.
+const inQueryChunk = 500
+
+// inArgs devuelve los marcadores de una consulta IN y sus argumentos.
+func inArgs(values []string) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return "(?" + strings.Repeat(", ?", len(values)-1) + ")", args
+}
+
+// GetReceipts devuelve, indexados por número, los recibos de la lista que ya
+// fueron importados.
+func (r *DatabaseRepository) GetReceipts(ctx context.Context, numbers []string) (map[string]Receipt, error) {
+	receipts := map[string]Receipt{}
+
+	for start := 0; start < len(numbers); start += inQueryChunk {
+		end := start + inQueryChunk
+		if end > len(numbers) {
+			end = len(numbers)
+		}
+
+		in, args := inArgs(numbers[start:end])
+		query := "SELECT receipt, subscriber, amount, payment_date, consecutive, create_at FROM mekanoreceipts WHERE receipt IN " + in
+
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var receipt Receipt
+			if err := rows.Scan(&receipt.Number, &receipt.Subscriber, &receipt.Amount, &receipt.PaymentDate, &receipt.Consecutive, &receipt.CreateAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			receipts[receipt.Number] = receipt
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return receipts, nil
+}
+
+// SaveReceipts registra en una sola transacción los recibos importados.
+func (r *DatabaseRepository) SaveReceipts(ctx context.Context, receipts []Receipt) error {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	rows := make([][]interface{}, 0, len(receipts))
+	for _, receipt := range receipts {
+		rows = append(rows, []interface{}{receipt.Number, receipt.Subscriber, receipt.Amount, receipt.PaymentDate, receipt.Consecutive, receipt.CreateAt})
+	}
+	if err := insertRows(ctx, tx, "INSERT INTO mekanoreceipts (receipt, subscriber, amount, payment_date, consecutive, create_at)", rows); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetInvoices devuelve, indexadas por CUFE, las facturas de la lista que ya
+// fueron exportadas.
+func (r *DatabaseRepository) GetInvoices(ctx context.Context, cufes []string) (map[string]Invoice, error) {
+	invoices := map[string]Invoice{}
+
+	for start := 0; start < len(cufes); start += inQueryChunk {
+		end := start + inQueryChunk
+		if end > len(cufes) {
+			end = len(cufes)
+		}
+
+		in, args := inArgs(cufes[start:end])
+		query := "SELECT cufe, number, subscriber, file_name, create_at FROM mekanoinvoices WHERE cufe IN " + in
+
+		rows, err := r.db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+
+		for rows.Next() {
+			var invoice Invoice
+			if err := rows.Scan(&invoice.Cufe, &invoice.Number, &invoice.Subscriber, &invoice.FileName, &invoice.CreateAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			invoices[invoice.Cufe] = invoice
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return invoices, nil
+}
+
+// SaveInvoices registra en una sola transacción las facturas exportadas.
+func (r *DatabaseRepository) SaveInvoices(ctx context.Context, invoices []Invoice) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows := make([][]interface{}, 0, len(invoices))
+	for _, invoice := range invoices {
+		rows = append(rows, []interface{}{invoice.Cufe, invoice.Number, invoice.Subscriber, invoice.FileName, invoice.CreateAt})
+	}
+	if err := insertRows(ctx, tx, "INSERT INTO mekanoinvoices (cufe, number, subscriber, file_name, create_at)", rows); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// insertRows inserta las filas con sentencias de varias filas, de a
+// inQueryChunk, en lugar de una sentencia por fila. Todas las filas deben
+// tener la misma cantidad de columnas que insert.
+func insertRows(ctx context.Context, tx *sql.Tx, insert string, rows [][]interface{}) error {
+	for start := 0; start < len(rows); start += inQueryChunk {
+		end := start + inQueryChunk
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		placeholders := "(?" + strings.Repeat(", ?", len(rows[start])-1) + ")"
+		query := insert + " VALUES " + placeholders + strings.Repeat(", "+placeholders, end-start-1)
+
+		var args []interface{}
+		for _, row := range rows[start:end] {
+			args = append(args, row...)
+		}
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+// GetConsecutive devuelve el último consecutivo asignado de la secuencia sin
+// reservar ninguno.
+func (r *DatabaseRepository) GetConsecutive(ctx context.Context, sequence string) (int, error) {
+	var value int
+	err := r.db.QueryRowContext(ctx, "SELECT value FROM mekanosequences WHERE name = ?", sequence).Scan(&value)
+	if err == sql.ErrNoRows {
+		return r.lastPaymentConsecutive(ctx, r.db)
+	}
+	return value, err
+}
+
+// ReserveConsecutives reserva n consecutivos de la secuencia. La fila de la
+// secuencia se bloquea dentro de una transacción que queda abierta hasta
+// confirmar o descartar la reserva, de modo que dos ejecuciones simultáneas
+// nunca reciben rangos superpuestos y un rango descartado no deja huecos.
+func (r *DatabaseRepository) ReserveConsecutives(ctx context.Context, sequence string, n int) (*Reservation, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var value int
+	err = tx.QueryRowContext(ctx, "SELECT value FROM mekanosequences WHERE name = ?"+r.dialect.forUpdate, sequence).Scan(&value)
+	if err == sql.ErrNoRows {
+		// Las instalaciones anteriores a la tabla de secuencias continúan
+		// desde el último consecutivo registrado en mekanopayments.
+		value, err = r.lastPaymentConsecutive(ctx, tx)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, "INSERT INTO mekanosequences (name, value) VALUES (?, ?)", sequence, value+n)
+		}
+	} else if err == nil {
+		_, err = tx.ExecContext(ctx, "UPDATE mekanosequences SET value = ? WHERE name = ?", value+n, sequence)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return &Reservation{First: value + 1, commit: tx.Commit, rollback: tx.Rollback}, nil
+}
+
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *DatabaseRepository) lastPaymentConsecutive(ctx context.Context, q queryRower) (int, error) {
+	var value sql.NullInt64
+	if err := q.QueryRowContext(ctx, "SELECT MAX(consecutive) FROM mekanopayments").Scan(&value); err != nil {
+		return 0, err
+	}
+	if !value.Valid {
+		return 0, ErrNoPaymentHistory
+	}
+	return int(value.Int64), nil
+}
+
+// SeedConsecutive inicializa la secuencia para que el próximo consecutivo
+// asignado sea first. Falla si la secuencia ya fue inicializada.
+func (r *DatabaseRepository) SeedConsecutive(ctx context.Context, sequence string, first int) error {
+	current, err := r.GetConsecutive(ctx, sequence)
+	if err == nil {
+		return fmt.Errorf("la secuencia %s ya está inicializada, último consecutivo %d", sequence, current)
+	}
+	if !errors.Is(err, ErrNoPaymentHistory) {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, "INSERT INTO mekanosequences (name, value) VALUES (?, ?)", sequence, first-1)
+	return err
+}
+
+// batchLineColumns son las columnas de mekanobatchlines con los campos de
+// MekanoDataStruct, en el orden de la interfaz.
+const batchLineColumns = "tipo, prefijo, numero, secuencia, fecha, cuenta, terceros, centro_costos, nota, debito, credito, base, aplica, " +
+	"tipo_anexo, prefijo_anexo, numero_anexo, usuario, signo, cuenta_cobrar, cuenta_pagar, nombre_tercero, nombre_centro, interfaz"
+
+// SaveBatch guarda en una sola transacción el lote y todas sus líneas, y
+// devuelve el id asignado.
+func (r *DatabaseRepository) SaveBatch(ctx context.Context, batch Batch) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var encoding sql.NullString
+	var crlf sql.NullBool
+	if batch.Output != nil {
+		encoding = sql.NullString{String: batch.Output.Encoding, Valid: true}
+		crlf = sql.NullBool{Bool: batch.Output.CRLF, Valid: true}
+	}
+	result, err := tx.ExecContext(ctx, "INSERT INTO mekanobatches (kind, file_name, checksum, operator, create_at, encoding, crlf) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		batch.Kind, batch.FileName, batch.Checksum, batch.Operator, batch.CreateAt, encoding, crlf)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	rows := make([][]interface{}, 0, len(batch.Lines))
+	for i, line := range batch.Lines {
+		row := []interface{}{id, i + 1}
+		for _, v := range interfaceRow(line) {
+			row = append(row, v)
+		}
+		rows = append(rows, row)
+	}
+	if err := insertRows(ctx, tx, "INSERT INTO mekanobatchlines (batch_id, line, "+batchLineColumns+")", rows); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetBatch devuelve el lote con sus líneas en el orden en que se exportaron.
+func (r *DatabaseRepository) GetBatch(ctx context.Context, id int64) (Batch, error) {
+	batch := Batch{ID: id}
+	var encoding sql.NullString
+	var crlf sql.NullBool
+	err := r.db.QueryRowContext(ctx, "SELECT kind, file_name, checksum, operator, create_at, encoding, crlf FROM mekanobatches WHERE id = ?", id).
+		Scan(&batch.Kind, &batch.FileName, &batch.Checksum, &batch.Operator, &batch.CreateAt, &encoding, &crlf)
+	if err == sql.ErrNoRows {
+		return Batch{}, fmt.Errorf("%w %d", ErrBatchNotFound, id)
+	}
+	if err != nil {
+		return Batch{}, err
+	}
+	if encoding.Valid {
+		batch.Output = &OutputOptions{Encoding: encoding.String, CRLF: crlf.Bool}
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT "+batchLineColumns+" FROM mekanobatchlines WHERE batch_id = ? ORDER BY line", id)
+	if err != nil {
+		return Batch{}, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var d MekanoDataStruct
+		err := rows.Scan(&d.Tipo, &d.Prefijo, &d.Numero, &d.Secuencia, &d.Fecha, &d.Cuenta, &d.Terceros, &d.CentroCostos, &d.Nota,
+			&d.Debito, &d.Credito, &d.Base, &d.Aplica, &d.TipoAnexo, &d.PrefijoAnexo, &d.NumeroAnexo, &d.Usuario, &d.Signo,
+			&d.CuentaCobrar, &d.CuentaPagar, &d.NombreTercero, &d.NombreCentro, &d.Interface)
+		if err != nil {
+			return Batch{}, err
+		}
+		batch.Lines = append(batch.Lines, d)
+	}
+	return batch, rows.Err()
+}
@@ -4,9 +4,45 @@ import (
 	"context"
 	"math/rand"
 	"reflect"
+	"sort"
+	"strconv"
+	"sync"
 	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
 )
 
+func TestMigrations(t *testing.T) {
+	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
+	if err != nil {
+		t.Fatalf("Error initializing the database")
+	}
+	ctx := context.Background()
+
+	if _, err := repository.MigrateUp(ctx, 0); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+
+	// Aplicar las migraciones dos veces no debe hacer nada.
+	applied, err := repository.MigrateUp(ctx, 0)
+	if err != nil {
+		t.Fatalf("Error applying migrations twice: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Expected no pending migrations, got %d", len(applied))
+	}
+
+	status, err := repository.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("Error getting migration status: %v", err)
+	}
+	for _, s := range status {
+		if !s.Applied {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+}
+
 func TestSavePayment(t *testing.T) {
 	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
 	if err != nil {
@@ -66,3 +102,140 @@ func TestGetPayment(t *testing.T) {
 	}
 
 }
+
+func TestSaveAndDeleteMappings(t *testing.T) {
+	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
+	if err != nil {
+		t.Fatalf("Error initializing the database")
+	}
+
+	mappings := config.Mappings{
+		Accounts: map[string]string{"PLAN SENIOR SIMETRICO": "41457057"},
+	}
+
+	err = repository.SaveMappings(context.Background(), mappings)
+	if err != nil {
+		t.Fatalf("Error saving mappings: %v", err)
+	}
+
+	stored, err := repository.GetMappings(context.Background())
+	if err != nil {
+		t.Fatalf("Error getting mappings: %v", err)
+	}
+
+	if stored.Accounts["PLAN SENIOR SIMETRICO"] != "41457057" {
+		t.Errorf("Expected account 41457057, got: %q", stored.Accounts["PLAN SENIOR SIMETRICO"])
+	}
+
+	err = repository.DeleteMapping(context.Background(), config.SectionAccounts, "PLAN SENIOR SIMETRICO")
+	if err != nil {
+		t.Fatalf("Error deleting mapping: %v", err)
+	}
+}
+
+func TestSaveReceipts(t *testing.T) {
+	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
+	if err != nil {
+		t.Fatalf("Error initializing the database")
+	}
+
+	receipt := Receipt{
+		Number:      strconv.Itoa(rand.Intn(1000000)),
+		Subscriber:  "5449",
+		Amount:      "75000",
+		PaymentDate: "01/07/2023",
+		Consecutive: rand.Intn(100),
+		CreateAt:    "2023-01-13",
+	}
+
+	err = repository.SaveReceipts(context.Background(), []Receipt{receipt})
+	if err != nil {
+		t.Fatalf("Error saving receipts: %v", err)
+	}
+
+	receipts, err := repository.GetReceipts(context.Background(), []string{receipt.Number, "no-existe"})
+	if err != nil {
+		t.Fatalf("Error getting receipts: %v", err)
+	}
+
+	expectedData := map[string]Receipt{receipt.Number: receipt}
+	if !reflect.DeepEqual(receipts, expectedData) {
+		t.Errorf("Expected data: %+v, got: %+v", expectedData, receipts)
+	}
+}
+
+func TestSaveInvoices(t *testing.T) {
+	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
+	if err != nil {
+		t.Fatalf("Error initializing the database")
+	}
+
+	invoice := Invoice{
+		Cufe:       strconv.Itoa(rand.Intn(1000000)),
+		Number:     "66137",
+		Subscriber: "3296",
+		FileName:   "billing_test.xlsx",
+		CreateAt:   "2023-01-13",
+	}
+
+	err = repository.SaveInvoices(context.Background(), []Invoice{invoice})
+	if err != nil {
+		t.Fatalf("Error saving invoices: %v", err)
+	}
+
+	invoices, err := repository.GetInvoices(context.Background(), []string{invoice.Cufe})
+	if err != nil {
+		t.Fatalf("Error getting invoices: %v", err)
+	}
+
+	expectedData := map[string]Invoice{invoice.Cufe: invoice}
+	if !reflect.DeepEqual(invoices, expectedData) {
+		t.Errorf("Expected data: %+v, got: %+v", expectedData, invoices)
+	}
+}
+
+func TestReserveConsecutives(t *testing.T) {
+	repository, err := NewDatabaseRepository("root:root@tcp(localhost:3306)/mekano_test")
+	if err != nil {
+		t.Fatalf("Error initializing the database")
+	}
+
+	const workers, size = 5, 10
+	firsts := make(chan int, workers)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := repository.ReserveConsecutives(context.Background(), SequenceRC, size)
+			if err == nil {
+				err = reservation.Commit()
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			firsts <- reservation.First
+		}()
+	}
+	wg.Wait()
+	close(firsts)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Error reserving consecutives: %v", err)
+	}
+
+	var reserved []int
+	for first := range firsts {
+		reserved = append(reserved, first)
+	}
+	sort.Ints(reserved)
+	for i := 1; i < len(reserved); i++ {
+		if reserved[i]-reserved[i-1] != size {
+			t.Errorf("Reserved ranges overlap or leave gaps: %v", reserved)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+	"github.com/mozillazg/go-unidecode"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// OutputOptions configura el formato del archivo de interfaz.
+type OutputOptions struct {
+	// Encoding es la codificación del archivo: utf-8 (por defecto),
+	// utf-8-bom, windows-1252 (ansi) o iso-8859-1 (latin1). Mekano en
+	// Windows lee la interfaz como ANSI.
+	Encoding string
+	// CRLF termina las líneas con \r\n en lugar de \n.
+	CRLF bool
+}
+
+// Validate verifica que la codificación sea soportada, para fallar antes de
+// reservar consecutivos.
+func (o OutputOptions) Validate() error {
+	_, _, err := o.encoding()
+	return err
+}
+
+// encoding devuelve la tabla de caracteres de salida, nil para UTF-8, e
+// indica si el archivo debe empezar con BOM.
+func (o OutputOptions) encoding() (*charmap.Charmap, bool, error) {
+	switch strings.ToLower(strings.TrimSpace(o.Encoding)) {
+	case "utf-8-bom", "utf8-bom", "utf-8-sig":
+		return nil, true, nil
+	}
+
+	enc, err := lookupEncoding(o.Encoding)
+	if err != nil {
+		return nil, false, fmt.Errorf("interfaz: %w", err)
+	}
+	cm, _ := enc.(*charmap.Charmap)
+	return cm, false, nil
+}
+
+// transliterate reemplaza los caracteres que cm no puede representar por su
+// equivalente ASCII, o por "?" si no lo tiene.
+func transliterate(cm *charmap.Charmap, s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if _, ok := cm.EncodeRune(r); ok {
+			b.WriteRune(r)
+		} else if t := unidecode.Unidecode(string(r)); t != "" {
+			b.WriteString(t)
+		} else {
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// interfacePath es la ruta donde se exporta la interfaz para Mekano.
+func (mr *mekanoRepository) interfacePath() string {
+	if mr.opts.ExportPath != "" {
+		return mr.opts.ExportPath
+	}
+	return config.Export{Dir: config.MekanoExportPath, File: config.DefaultExportFile}.Path()
+}
+
+// exporterFile escribe la interfaz en path. Si ya existe un archivo, se
+// conserva con la fecha y hora en el nombre antes de reemplazarlo, para que
+// Mekano nunca vea un archivo a medio escribir ni se pierda uno sin importar.
+func exporterFile(path string, mekanoData []MekanoDataStruct, opts OutputOptions) error {
+	tmp, err := writeInterfaceTemp(path, mekanoData, opts)
+	if err != nil {
+		return fmt.Errorf("no se pudo escribir la interfaz %s: %w", path, err)
+	}
+	defer os.Remove(tmp)
+
+	backup, err := backupFile(path)
+	if err != nil {
+		return fmt.Errorf("no se pudo respaldar la interfaz anterior %s: %w", path, err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		if backup != "" {
+			os.Remove(backup)
+		}
+		return fmt.Errorf("no se pudo escribir la interfaz %s: %w", path, err)
+	}
+
+	if backup != "" {
+		log.Printf("Interfaz anterior respaldada en %s", backup)
+	}
+	log.Printf("Interfaz escrita en %s", path)
+	return nil
+}
+
+// writeInterfaceFile escribe las líneas en el formato de interfaz de Mekano,
+// reemplazando path solo si la escritura termina bien.
+func writeInterfaceFile(path string, mekanoData []MekanoDataStruct, opts OutputOptions) error {
+	tmp, err := writeInterfaceTemp(path, mekanoData, opts)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp)
+
+	return os.Rename(tmp, path)
+}
+
+// writeInterfaceTemp escribe las líneas en un archivo temporal junto a path,
+// para poder reemplazarlo con un rename, y devuelve su ruta.
+func writeInterfaceTemp(path string, mekanoData []MekanoDataStruct, opts OutputOptions) (string, error) {
+	cm, bom, err := opts.encoding()
+	if err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+
+	buffered := bufio.NewWriter(tmp)
+	var out io.Writer = buffered
+	if bom {
+		buffered.WriteString("\uFEFF")
+	}
+	var encoder *transform.Writer
+	if cm != nil {
+		encoder = transform.NewWriter(buffered, cm.NewEncoder())
+		out = encoder
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Comma = ','
+	writer.UseCRLF = opts.CRLF
+
+	for _, data := range mekanoData {
+		row := interfaceRow(data)
+		if cm != nil {
+			for i := range row {
+				row[i] = transliterate(cm, row[i])
+			}
+		}
+		if err := writer.Write(row); err != nil {
+			break
+		}
+	}
+	writer.Flush()
+
+	err = writer.Error()
+	if err == nil && encoder != nil {
+		err = encoder.Close()
+	}
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", err
+	}
+	return tmp.Name(), nil
+}
+
+// backupFile copia path a un archivo con la fecha y hora antes de la
+// extensión, por ejemplo CONTABLE.20230113-150405.txt, y devuelve su nombre.
+// path no se mueve, para que exista en todo momento hasta que el rename lo
+// reemplace. La copia es un enlace duro cuando el sistema de archivos lo
+// permite. Si path no existe no hace nada.
+func backupFile(path string) (string, error) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext) + "." + time.Now().Format("20060102-150405")
+
+	backup := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backup); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		backup = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+
+	if err := os.Link(path, backup); err == nil {
+		return backup, nil
+	}
+	if err := copyFile(path, backup); err != nil {
+		os.Remove(backup)
+		return "", err
+	}
+	return backup, nil
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+// PaymentRun es una ejecución de pagos del historial con sus totales por
+// caja.
+type PaymentRun struct {
+	Date    string `json:"fecha"`
+	BatchID int64  `json:"lote,omitempty"`
+	paymentStatistics
+}
+
+// BillingRun es una ejecución de facturación del historial.
+type BillingRun struct {
+	Date     string `json:"fecha"`
+	FileName string `json:"archivo"`
+	BatchID  int64  `json:"lote,omitempty"`
+	billingStatistics
+}
+
+// History agrupa las ejecuciones de pagos y facturación que cumplen un filtro.
+type History struct {
+	Payments []PaymentRun `json:"pagos"`
+	Billings []BillingRun `json:"facturacion"`
+}
+
+// LoadHistory consulta las ejecuciones registradas. Las ejecuciones de pagos
+// anteriores a que se guardaran los totales los calculan a partir de las
+// líneas de su lote, y las anteriores a los lotes no tienen totales por caja.
+func LoadHistory(ctx context.Context, dr DatabaseRepositoryInterface, m config.Mappings, filter HistoryFilter) (History, error) {
+	h := History{Payments: []PaymentRun{}, Billings: []BillingRun{}}
+
+	payments, err := dr.ListPayments(ctx, filter)
+	if err != nil {
+		return History{}, err
+	}
+	for _, p := range payments {
+		if p.Rows > 0 {
+			h.Payments = append(h.Payments, PaymentRun{Date: p.CreateAt, BatchID: p.BatchID, paymentStatistics: paymentStatistics{
+				FileName:    p.FileName,
+				RangoRC:     fmt.Sprintf("%d-%d", p.FirstRC, p.Consecutive),
+				Filas:       p.Rows,
+				Bancolombia: p.Bancolombia,
+				Davivienda:  p.Davivienda,
+				Susuerte:    p.Susuerte,
+				PayU:        p.PayU,
+				Efectivo:    p.Cash,
+				Total:       p.Total,
+			}})
+			continue
+		}
+
+		var lines []MekanoDataStruct
+		if p.BatchID != 0 {
+			batch, err := dr.GetBatch(ctx, p.BatchID)
+			if err != nil {
+				return History{}, err
+			}
+			lines = batch.Lines
+			// Las líneas del lote tienen el rango exacto de RC, aun cuando
+			// no hay una ejecución anterior de la cual deducirlo.
+			if first, ok := firstRC(lines); ok {
+				p.FirstRC = first
+			}
+		}
+
+		s := newPaymentStatistics(p.FileName, lines, p.FirstRC-1, p.Consecutive, m)
+		if p.FirstRC == 0 {
+			s.RangoRC = fmt.Sprintf("?-%d", p.Consecutive)
+			s.Filas = 0
+		}
+		h.Payments = append(h.Payments, PaymentRun{Date: p.CreateAt, BatchID: p.BatchID, paymentStatistics: s})
+	}
+
+	billings, err := dr.ListBillings(ctx, filter)
+	if err != nil {
+		return History{}, err
+	}
+	for _, b := range billings {
+		h.Billings = append(h.Billings, BillingRun{
+			Date:     b.CreateAt,
+			FileName: b.FileName,
+			BatchID:  b.BatchID,
+			billingStatistics: billingStatistics{
+				Debito:  b.Debit,
+				Credito: b.Credit,
+				Base:    b.Base,
+			},
+		})
+	}
+	return h, nil
+}
+
+// firstRC devuelve el menor número de RC de las líneas de un lote de pagos.
+func firstRC(lines []MekanoDataStruct) (int, bool) {
+	first, ok := 0, false
+	for _, l := range lines {
+		n, err := strconv.Atoi(l.Numero)
+		if l.Tipo != "RC" || err != nil {
+			continue
+		}
+		if !ok || n < first {
+			first, ok = n, true
+		}
+	}
+	return first, ok
+}
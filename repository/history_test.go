@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestLoadHistory(t *testing.T) {
+	ctx := context.Background()
+	dr := newSQLiteTestRepository(t)
+
+	// Una ejecución anterior a los lotes, sin totales por caja.
+	err := dr.SavePayment(ctx, Payment{Consecutive: 15000, CreateAt: "2023-01-13", FileName: "pagos_enero.xlsx"})
+	if err != nil {
+		t.Fatalf("Error al guardar el pago: %v", err)
+	}
+
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if _, err := mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de facturación: %v", err)
+	}
+
+	h, err := LoadHistory(ctx, dr, config.DefaultMappings(), HistoryFilter{})
+	if err != nil {
+		t.Fatalf("Error al consultar el historial: %v", err)
+	}
+	if len(h.Payments) != 2 || len(h.Billings) != 1 {
+		t.Fatalf("Se esperaban 2 pagos y 1 facturación, se obtuvo: %+v", h)
+	}
+
+	if h.Payments[0].RangoRC != "?-15000" || h.Payments[0].Total != 0 {
+		t.Errorf("Ejecución anterior inesperada: %+v", h.Payments[0])
+	}
+	run := h.Payments[1]
+	if run.RangoRC != "15001-15001" || run.Susuerte != 75000_00 || run.Total != 75000_00 || run.BatchID == 0 {
+		t.Errorf("Ejecución de pagos inesperada: %+v", run)
+	}
+	if h.Billings[0].Debito == 0 || h.Billings[0].Debito != h.Billings[0].Credito || h.Billings[0].BatchID == 0 {
+		t.Errorf("Ejecución de facturación inesperada: %+v", h.Billings[0])
+	}
+
+	today := time.Now().Format("2006-01-02")
+	h, err = LoadHistory(ctx, dr, config.DefaultMappings(), HistoryFilter{From: today, File: "payment"})
+	if err != nil {
+		t.Fatalf("Error al consultar el historial: %v", err)
+	}
+	if len(h.Payments) != 1 || len(h.Billings) != 0 || h.Payments[0].RangoRC != "15001-15001" {
+		t.Errorf("El filtro devolvió ejecuciones inesperadas: %+v", h)
+	}
+}
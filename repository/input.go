@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+)
+
+// CSVOptions configura la lectura de archivos de entrada en formato CSV.
+type CSVOptions struct {
+	// Delimiter es el separador de campos; por defecto ','.
+	Delimiter rune
+	// Encoding es la codificación del archivo: utf-8 (por defecto), latin1
+	// (iso-8859-1) o windows-1252.
+	Encoding string
+}
+
+// lookupEncoding devuelve la codificación con el nombre indicado. Para UTF-8
+// devuelve nil, ya que no requiere transformación.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "utf-8", "utf8":
+		return nil, nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return charmap.ISO8859_1, nil
+	case "windows-1252", "cp1252", "ansi":
+		return charmap.Windows1252, nil
+	default:
+		return nil, fmt.Errorf("codificación no soportada: %q", name)
+	}
+}
+
+// rowSource recorre una a una las filas de un archivo de entrada.
+type rowSource interface {
+	Next() bool
+	Columns() ([]string, error)
+	Error() error
+	Close() error
+}
+
+// openRows abre la primera hoja de un archivo .xlsx o un archivo .csv para
+// recorrer sus filas.
+func openRows(path string, opts CSVOptions) (rowSource, error) {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return openCSVRows(path, opts)
+	}
+
+	xlsx, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := xlsx.Rows(xlsx.GetSheetName(0))
+	if err != nil {
+		xlsx.Close()
+		return nil, err
+	}
+	return &xlsxRows{file: xlsx, rows: rows}, nil
+}
+
+// xlsxRows adapta el iterador de filas de excelize a rowSource.
+type xlsxRows struct {
+	file *excelize.File
+	rows *excelize.Rows
+}
+
+func (x *xlsxRows) Next() bool {
+	return x.rows.Next()
+}
+
+func (x *xlsxRows) Columns() ([]string, error) {
+	return x.rows.Columns()
+}
+
+func (x *xlsxRows) Error() error {
+	return x.rows.Error()
+}
+
+func (x *xlsxRows) Close() error {
+	if err := x.rows.Close(); err != nil {
+		x.file.Close()
+		return err
+	}
+	return x.file.Close()
+}
+
+// csvRows recorre las filas de un archivo CSV.
+type csvRows struct {
+	file   *os.File
+	reader *csv.Reader
+	row    []string
+	line   int
+	err    error
+}
+
+func openCSVRows(path string, opts CSVOptions) (*csvRows, error) {
+	enc, err := lookupEncoding(opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = file
+	if enc != nil {
+		r = transform.NewReader(file, enc.NewDecoder())
+	}
+
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	return &csvRows{file: file, reader: reader}, nil
+}
+
+func (c *csvRows) Next() bool {
+	if c.err != nil {
+		return false
+	}
+
+	row, err := c.reader.Read()
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		c.err = fmt.Errorf("%s: %w", c.file.Name(), err)
+		return false
+	}
+
+	// Excel agrega una marca BOM al inicio de los CSV exportados en UTF-8.
+	if c.line == 0 && len(row) > 0 {
+		row[0] = strings.TrimPrefix(row[0], "\ufeff")
+	}
+	c.line++
+	c.row = row
+	return true
+}
+
+func (c *csvRows) Columns() ([]string, error) {
+	return c.row, nil
+}
+
+func (c *csvRows) Error() error {
+	return c.err
+}
+
+func (c *csvRows) Close() error {
+	return c.file.Close()
+}
+
+// ParseDelimiter convierte el valor del flag de separador en una runa. Acepta
+// "\t" o "tab" para tabulaciones.
+func ParseDelimiter(s string) (rune, error) {
+	switch s {
+	case "":
+		return ',', nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+	if utf8.RuneCountInString(s) != 1 {
+		return 0, fmt.Errorf("el separador debe ser un solo carácter: %q", s)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return r, nil
+}
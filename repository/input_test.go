@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestReadRowsCSV(t *testing.T) {
+	content, err := charmap.ISO8859_1.NewEncoder().String("Nro Abonado;Identificación;Cliente\n3724;797339211;JOSÉ NUÑEZ\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "facturacion.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, err := openRows(path, CSVOptions{Delimiter: ';', Encoding: "latin1"})
+	if err != nil {
+		t.Fatalf("Error al leer el CSV: %v", err)
+	}
+	defer source.Close()
+
+	var rows [][]string
+	for source.Next() {
+		row, _ := source.Columns()
+		rows = append(rows, row)
+	}
+	if err := source.Error(); err != nil {
+		t.Fatalf("Error al leer el CSV: %v", err)
+	}
+
+	expected := [][]string{
+		{"Nro Abonado", "Identificación", "Cliente"},
+		{"3724", "797339211", "JOSÉ NUÑEZ"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Esperado: %q, obtenido: %q", expected, rows)
+	}
+}
+
+func TestReadRowsCSVWithBOM(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "extras.csv")
+	if err := os.WriteFile(path, []byte("\ufeffABONADO,ITEM,BASE\n4755,UNIFI,70000\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := readAll(openSheet(extrasSchema, path, CSVOptions{}))
+	if err != nil {
+		t.Fatalf("No se resolvieron los encabezados: %v", err)
+	}
+	if len(records) != 1 || records[0].get(colExtraItem) != "UNIFI" {
+		t.Errorf("Filas inesperadas: %+v", records)
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	cases := map[string]rune{"": ',', ";": ';', `\t`: '\t', "tab": '\t', "|": '|'}
+	for in, want := range cases {
+		got, err := ParseDelimiter(in)
+		if err != nil || got != want {
+			t.Errorf("ParseDelimiter(%q) = %q, %v; se esperaba %q", in, got, err, want)
+		}
+	}
+
+	if _, err := ParseDelimiter(";;"); err == nil {
+		t.Errorf("Se esperaba un error para un separador de varios caracteres")
+	}
+}
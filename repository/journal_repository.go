@@ -0,0 +1,402 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+// ErrOffline indica que la operación necesita la base de datos central.
+var ErrOffline = errors.New("operación no disponible sin conexión a la base de datos")
+
+// ErrNoOfflineConsecutive indica que el diario no conoce el último
+// consecutivo de la base central, por lo que no puede asignar nuevos.
+var ErrNoOfflineConsecutive = errors.New("el diario sin conexión no conoce el último consecutivo: ejecute mekano-cli sync con conexión antes de trabajar sin ella")
+
+// Tipos de entrada del diario.
+const (
+	journalCheckpoint = "checkpoint"
+	journalReserve    = "reserve"
+	journalPayment    = "payment"
+	journalBilling    = "billing"
+	journalReceipts   = "receipts"
+	journalInvoices   = "invoices"
+	journalBatch      = "batch"
+)
+
+// journalEntry es una línea del diario. Checkpoint guarda el último
+// consecutivo conocido de la base central y no se sincroniza; las demás
+// entradas son cambios hechos sin conexión que sync envía a la base central.
+type journalEntry struct {
+	Kind     string    `json:"kind"`
+	At       string    `json:"at"`
+	Sequence string    `json:"sequence,omitempty"`
+	Value    int       `json:"value,omitempty"`
+	First    int       `json:"first,omitempty"`
+	Count    int       `json:"count,omitempty"`
+	Payment  *Payment  `json:"payment,omitempty"`
+	Billing  *Billing  `json:"billing,omitempty"`
+	Receipts []Receipt `json:"receipts,omitempty"`
+	Invoices []Invoice `json:"invoices,omitempty"`
+	Batch    *Batch    `json:"batch,omitempty"`
+	// CentralID es el id que la base central asignó a un lote ya enviado
+	// por un sync que no terminó; los pagos y facturaciones pendientes que
+	// lo referencian se envían con este id.
+	CentralID int64 `json:"central_id,omitempty"`
+}
+
+// JournalRepository implementa DatabaseRepositoryInterface sobre un archivo
+// local de líneas JSON, para trabajar sin conexión a la base central. Las
+// consultas se responden con lo registrado en el diario.
+type JournalRepository struct {
+	path string
+
+	mu        sync.Mutex
+	entries   []journalEntry
+	sequences map[string]int
+	payments  []Payment
+	billings  []Billing
+	receipts  map[string]Receipt
+	invoices  map[string]Invoice
+	batches   []Batch
+}
+
+// NewJournalRepository abre el diario, creándolo si no existe.
+func NewJournalRepository(path string) (*JournalRepository, error) {
+	j := &JournalRepository{path: path}
+	j.reset()
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s, línea %d: %w", path, line, err)
+		}
+		j.apply(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *JournalRepository) reset() {
+	j.entries = nil
+	j.sequences = map[string]int{}
+	j.payments = nil
+	j.billings = nil
+	j.receipts = map[string]Receipt{}
+	j.invoices = map[string]Invoice{}
+	j.batches = nil
+}
+
+// apply actualiza el estado en memoria con una entrada del diario.
+func (j *JournalRepository) apply(entry journalEntry) {
+	j.entries = append(j.entries, entry)
+
+	switch entry.Kind {
+	case journalCheckpoint:
+		j.sequences[entry.Sequence] = entry.Value
+	case journalReserve:
+		j.sequences[entry.Sequence] = entry.First + entry.Count - 1
+	case journalPayment:
+		j.payments = append(j.payments, *entry.Payment)
+	case journalBilling:
+		j.billings = append(j.billings, *entry.Billing)
+	case journalReceipts:
+		for _, receipt := range entry.Receipts {
+			j.receipts[receipt.Number] = receipt
+		}
+	case journalInvoices:
+		for _, invoice := range entry.Invoices {
+			j.invoices[invoice.Cufe] = invoice
+		}
+	case journalBatch:
+		j.batches = append(j.batches, *entry.Batch)
+	}
+}
+
+// append agrega una entrada al final del diario. Debe llamarse con mu tomado.
+func (j *JournalRepository) append(entry journalEntry) error {
+	entry.At = time.Now().Format("2006-01-02 15:04:05")
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	j.apply(entry)
+	return nil
+}
+
+// rewrite reemplaza el contenido del diario por entries. Debe llamarse con mu
+// tomado.
+func (j *JournalRepository) rewrite(entries []journalEntry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.path); err != nil {
+		return err
+	}
+
+	j.reset()
+	for _, entry := range entries {
+		j.apply(entry)
+	}
+	return nil
+}
+
+func (j *JournalRepository) GetPayment(ctx context.Context) (Payment, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if len(j.payments) == 0 {
+		return Payment{}, ErrNoPaymentHistory
+	}
+	return j.payments[len(j.payments)-1], nil
+}
+
+func (j *JournalRepository) SavePayment(ctx context.Context, payment Payment) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(journalEntry{Kind: journalPayment, Payment: &payment})
+}
+
+func (j *JournalRepository) SaveBilling(ctx context.Context, billing Billing) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(journalEntry{Kind: journalBilling, Billing: &billing})
+}
+
+// ListPayments devuelve las ejecuciones de pagos hechas sin conexión.
+func (j *JournalRepository) ListPayments(ctx context.Context, filter HistoryFilter) ([]Payment, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var payments []Payment
+	for i, payment := range j.payments {
+		if payment.FirstRC == 0 && i > 0 {
+			payment.FirstRC = j.payments[i-1].Consecutive + 1
+		}
+		if filter.match(payment.CreateAt, payment.FileName) {
+			payments = append(payments, payment)
+		}
+	}
+	return payments, nil
+}
+
+// ListBillings devuelve las ejecuciones de facturación hechas sin conexión.
+func (j *JournalRepository) ListBillings(ctx context.Context, filter HistoryFilter) ([]Billing, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var billings []Billing
+	for _, billing := range j.billings {
+		if filter.match(billing.CreateAt, billing.FileName) {
+			billings = append(billings, billing)
+		}
+	}
+	return billings, nil
+}
+
+// GetMappings no devuelve mapeos: sin conexión se usan los del archivo de
+// configuración o los compilados.
+func (j *JournalRepository) GetMappings(ctx context.Context) (config.Mappings, error) {
+	return config.Mappings{}, nil
+}
+
+func (j *JournalRepository) SaveMappings(ctx context.Context, mappings config.Mappings) error {
+	return ErrOffline
+}
+
+func (j *JournalRepository) DeleteMapping(ctx context.Context, section, name string) error {
+	return ErrOffline
+}
+
+// GetReceipts solo conoce los recibos importados sin conexión; los
+// importados en la base central se detectan como conflicto al sincronizar.
+func (j *JournalRepository) GetReceipts(ctx context.Context, numbers []string) (map[string]Receipt, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	receipts := map[string]Receipt{}
+	for _, number := range numbers {
+		if receipt, ok := j.receipts[number]; ok {
+			receipts[number] = receipt
+		}
+	}
+	return receipts, nil
+}
+
+func (j *JournalRepository) SaveReceipts(ctx context.Context, receipts []Receipt) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(journalEntry{Kind: journalReceipts, Receipts: receipts})
+}
+
+// GetInvoices solo conoce las facturas exportadas sin conexión.
+func (j *JournalRepository) GetInvoices(ctx context.Context, cufes []string) (map[string]Invoice, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	invoices := map[string]Invoice{}
+	for _, cufe := range cufes {
+		if invoice, ok := j.invoices[cufe]; ok {
+			invoices[cufe] = invoice
+		}
+	}
+	return invoices, nil
+}
+
+func (j *JournalRepository) SaveInvoices(ctx context.Context, invoices []Invoice) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.append(journalEntry{Kind: journalInvoices, Invoices: invoices})
+}
+
+func (j *JournalRepository) GetConsecutive(ctx context.Context, sequence string) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	value, ok := j.sequences[sequence]
+	if !ok {
+		return 0, ErrNoOfflineConsecutive
+	}
+	return value, nil
+}
+
+// ReserveConsecutives continúa la secuencia desde el último consecutivo
+// conocido. La reserva se escribe en el diario al confirmarla, y el diario
+// queda bloqueado hasta entonces. Si otra oficina asignó los mismos números
+// en la base central, sync lo informa como conflicto.
+func (j *JournalRepository) ReserveConsecutives(ctx context.Context, sequence string, n int) (*Reservation, error) {
+	j.mu.Lock()
+
+	value, ok := j.sequences[sequence]
+	if !ok {
+		j.mu.Unlock()
+		return nil, ErrNoOfflineConsecutive
+	}
+	return &Reservation{
+		First: value + 1,
+		commit: func() error {
+			defer j.mu.Unlock()
+			return j.append(journalEntry{Kind: journalReserve, Sequence: sequence, First: value + 1, Count: n})
+		},
+		rollback: func() error {
+			j.mu.Unlock()
+			return nil
+		},
+	}, nil
+}
+
+// El diario no tiene esquema: las migraciones solo aplican a la base central.
+
+func (j *JournalRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return nil, nil
+}
+
+func (j *JournalRepository) MigrateUp(ctx context.Context, steps int) ([]Migration, error) {
+	return nil, nil
+}
+
+func (j *JournalRepository) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	return nil, nil
+}
+
+// SeedConsecutive fija el último consecutivo conocido del diario para que el
+// próximo asignado sea first.
+func (j *JournalRepository) SeedConsecutive(ctx context.Context, sequence string, first int) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if current, ok := j.sequences[sequence]; ok {
+		return fmt.Errorf("la secuencia %s ya está inicializada, último consecutivo %d", sequence, current)
+	}
+	return j.append(journalEntry{Kind: journalCheckpoint, Sequence: sequence, Value: first - 1})
+}
+
+// SaveBatch registra el lote en el diario con un id local, que cambia al
+// sincronizarlo con la base central. El id sigue al mayor del diario, para
+// no repetir el de un lote que un sync incompleto dejó en él.
+func (j *JournalRepository) SaveBatch(ctx context.Context, batch Batch) (int64, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	batch.ID = 1
+	for _, b := range j.batches {
+		if b.ID >= batch.ID {
+			batch.ID = b.ID + 1
+		}
+	}
+	if err := j.append(journalEntry{Kind: journalBatch, Batch: &batch}); err != nil {
+		return 0, err
+	}
+	return batch.ID, nil
+}
+
+// GetBatch busca un lote por su id local en el diario.
+func (j *JournalRepository) GetBatch(ctx context.Context, id int64) (Batch, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, batch := range j.batches {
+		if batch.ID == id {
+			return batch, nil
+		}
+	}
+	return Batch{}, fmt.Errorf("%w %d en el diario", ErrBatchNotFound, id)
+}
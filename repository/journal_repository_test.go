@@ -0,0 +1,246 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestJournalRepositoryReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	ctx := context.Background()
+
+	journal, err := NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error opening journal: %v", err)
+	}
+
+	if _, err := journal.ReserveConsecutives(ctx, SequenceRC, 2); !errors.Is(err, ErrNoOfflineConsecutive) {
+		t.Fatalf("Expected ErrNoOfflineConsecutive, got: %v", err)
+	}
+	if err := journal.SeedConsecutive(ctx, SequenceRC, 15000); err != nil {
+		t.Fatalf("Error seeding journal: %v", err)
+	}
+	reservation, err := journal.ReserveConsecutives(ctx, SequenceRC, 2)
+	if err != nil || reservation.First != 15000 {
+		t.Fatalf("Expected first consecutive 15000, got: %+v, %v", reservation, err)
+	}
+	if err := reservation.Commit(); err != nil {
+		t.Fatalf("Error committing reservation: %v", err)
+	}
+	if err := journal.SaveReceipts(ctx, []Receipt{{Number: "107376", Consecutive: 15000}}); err != nil {
+		t.Fatalf("Error saving receipts: %v", err)
+	}
+	if err := journal.SaveMappings(ctx, config.Mappings{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got: %v", err)
+	}
+
+	// Al reabrir el diario se recupera el estado.
+	journal, err = NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error reopening journal: %v", err)
+	}
+	consecutive, err := journal.GetConsecutive(ctx, SequenceRC)
+	if err != nil || consecutive != 15001 {
+		t.Errorf("Expected consecutive 15001, got: %d, %v", consecutive, err)
+	}
+	receipts, err := journal.GetReceipts(ctx, []string{"107376", "107377"})
+	if err != nil || len(receipts) != 1 {
+		t.Errorf("Expected receipt 107376, got: %+v, %v", receipts, err)
+	}
+}
+
+func TestMekanoOfflineSync(t *testing.T) {
+	ctx := context.Background()
+	central := newSQLiteTestRepository(t)
+	if err := central.SeedConsecutive(ctx, SequenceRC, 15001); err != nil {
+		t.Fatalf("Error al inicializar la base central: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error al abrir el diario: %v", err)
+	}
+
+	// Sin cambios, sync solo trae el último consecutivo de la base central.
+	if _, err := journal.Sync(ctx, central, false); err != nil {
+		t.Fatalf("Error al sincronizar: %v", err)
+	}
+
+	mekano := NewMekanoRepository(journal, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos sin conexión: %v", err)
+	}
+
+	report, err := journal.Sync(ctx, central, false)
+	if err != nil {
+		t.Fatalf("Error al sincronizar: %v", err)
+	}
+	if report.Payments != 1 || report.Receipts != 1 || report.Sequences[SequenceRC] != 15001 {
+		t.Errorf("Reporte de sincronización inesperado: %+v", report)
+	}
+	if _, err := os.Stat(report.Archive); err != nil {
+		t.Errorf("No se archivó el diario sincronizado: %v", err)
+	}
+
+	receipts, err := central.GetReceipts(ctx, []string{"107376"})
+	if err != nil || receipts["107376"].Consecutive != 15001 {
+		t.Errorf("Recibo sincronizado inesperado: %+v, %v", receipts, err)
+	}
+
+	// Un segundo sync no debe volver a enviar nada.
+	report, err = journal.Sync(ctx, central, false)
+	if err != nil || report.Payments != 0 || report.Receipts != 0 {
+		t.Errorf("El segundo sync no debe enviar cambios: %+v, %v", report, err)
+	}
+}
+
+func TestMekanoOfflineSyncConflicts(t *testing.T) {
+	ctx := context.Background()
+	central := newSQLiteTestRepository(t)
+	if err := central.SeedConsecutive(ctx, SequenceRC, 15001); err != nil {
+		t.Fatalf("Error al inicializar la base central: %v", err)
+	}
+
+	journal, err := NewJournalRepository(filepath.Join(t.TempDir(), "journal.jsonl"))
+	if err != nil {
+		t.Fatalf("Error al abrir el diario: %v", err)
+	}
+	if _, err := journal.Sync(ctx, central, false); err != nil {
+		t.Fatalf("Error al sincronizar: %v", err)
+	}
+
+	mekano := NewMekanoRepository(journal, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos sin conexión: %v", err)
+	}
+
+	// Mientras tanto otra oficina importa el mismo archivo en la base central.
+	online := NewMekanoRepository(central, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	if _, err := online.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos en línea: %v", err)
+	}
+
+	report, err := journal.Sync(ctx, central, false)
+	if !errors.Is(err, ErrSyncConflicts) {
+		t.Fatalf("Se esperaba ErrSyncConflicts, se obtuvo: %v", err)
+	}
+	if len(report.Conflicts) != 2 {
+		t.Errorf("Se esperaban conflictos de consecutivo y recibo: %+v", report.Conflicts)
+	}
+
+	// Con force se omite el recibo repetido y se avanza la secuencia.
+	report, err = journal.Sync(ctx, central, true)
+	if err != nil {
+		t.Fatalf("Error al sincronizar con force: %v", err)
+	}
+	if report.Receipts != 0 || report.Payments != 1 || report.Sequences[SequenceRC] != 15001 {
+		t.Errorf("Reporte de sincronización inesperado: %+v", report)
+	}
+}
+
+// failingPaymentRepository simula un corte al registrar la ejecución de
+// pagos en la base central, después de registrar su lote.
+type failingPaymentRepository struct {
+	DatabaseRepositoryInterface
+}
+
+func (f failingPaymentRepository) SavePayment(ctx context.Context, payment Payment) error {
+	return errors.New("connection reset by peer")
+}
+
+func TestMekanoOfflineSyncRetryKeepsBatch(t *testing.T) {
+	ctx := context.Background()
+	central := newSQLiteTestRepository(t)
+	if err := central.SeedConsecutive(ctx, SequenceRC, 15001); err != nil {
+		t.Fatalf("Error al inicializar la base central: %v", err)
+	}
+	// La base central ya tiene un lote, para que los ids no coincidan.
+	if _, err := central.SaveBatch(ctx, Batch{Kind: BatchBilling, FileName: "billing.xlsx"}); err != nil {
+		t.Fatalf("Error al registrar el lote: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	journal, err := NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error al abrir el diario: %v", err)
+	}
+	if _, err := journal.Sync(ctx, central, false); err != nil {
+		t.Fatalf("Error al sincronizar: %v", err)
+	}
+
+	mekano := NewMekanoRepository(journal, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos sin conexión: %v", err)
+	}
+
+	if _, err := journal.Sync(ctx, failingPaymentRepository{central}, false); err == nil {
+		t.Fatal("Se esperaba un error al registrar la ejecución")
+	}
+
+	// El diario reabierto conserva el lote enviado y no repite su id local.
+	journal, err = NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error al reabrir el diario: %v", err)
+	}
+	id, err := journal.SaveBatch(ctx, Batch{Kind: BatchBilling, FileName: "offline.xlsx"})
+	if err != nil || id != 2 {
+		t.Errorf("Se esperaba el id local 2, se obtuvo: %d, %v", id, err)
+	}
+
+	report, err := journal.Sync(ctx, central, false)
+	if err != nil {
+		t.Fatalf("Error al reintentar la sincronización: %v", err)
+	}
+	if report.Payments != 1 || report.Receipts != 0 || report.Batches[1] != 2 || report.Batches[2] != 3 {
+		t.Errorf("Reporte de sincronización inesperado: %+v", report)
+	}
+
+	payments, err := central.ListPayments(ctx, HistoryFilter{})
+	if err != nil || len(payments) != 1 || payments[0].BatchID != 2 {
+		t.Fatalf("La ejecución debe quedar enlazada al lote central: %+v, %v", payments, err)
+	}
+	batch, err := central.GetBatch(ctx, payments[0].BatchID)
+	if err != nil || batch.Kind != BatchPayment {
+		t.Errorf("Lote central inesperado: %+v, %v", batch, err)
+	}
+}
+
+func TestJournalBatchKeepsOutput(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+
+	journal, err := NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error al abrir el diario: %v", err)
+	}
+	lines := []MekanoDataStruct{{Tipo: "RC", Prefijo: "_", Numero: "15001", Secuencia: "1", Nota: "RECAUDO CAJA ÑUÑOA"}}
+	id, err := journal.SaveBatch(ctx, Batch{Kind: BatchPayment, FileName: "payment.xlsx", Output: &OutputOptions{Encoding: "windows-1252", CRLF: true}, Lines: lines})
+	if err != nil {
+		t.Fatalf("Error al guardar el lote: %v", err)
+	}
+
+	journal, err = NewJournalRepository(path)
+	if err != nil {
+		t.Fatalf("Error al reabrir el diario: %v", err)
+	}
+	batch, err := journal.GetBatch(ctx, id)
+	if err != nil || batch.Output == nil || !batch.Output.CRLF || batch.Output.Encoding != "windows-1252" {
+		t.Fatalf("El lote debe conservar su formato: %+v, %v", batch.Output, err)
+	}
+
+	reexported := filepath.Join(t.TempDir(), "CONTABLE.txt")
+	if err := WriteBatch(reexported, batch, *batch.Output); err != nil {
+		t.Fatalf("Error al regenerar el lote: %v", err)
+	}
+	content, err := os.ReadFile(reexported)
+	if err != nil || !strings.HasSuffix(string(content), "\r\n") || !strings.Contains(string(content), "\xd1") {
+		t.Errorf("La interfaz regenerada debe usar CRLF y windows-1252: %q, %v", content, err)
+	}
+}
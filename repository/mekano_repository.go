@@ -2,20 +2,16 @@ package repository
 
 import (
 	"context"
-	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math"
-	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/OzkrOssa/mekano-cli/config"
 	"github.com/mozillazg/go-unidecode"
-	"github.com/xuri/excelize/v2"
 )
 
 type MekanoDataStruct struct {
@@ -47,59 +43,283 @@ type MekanoDataStruct struct {
 type mekanoInterface interface {
 	Payment(file string) ([]MekanoDataStruct, error)
 	Billing(file string, extras string) ([]MekanoDataStruct, error)
+	RebuildPayment(file string, run Payment) ([]MekanoDataStruct, error)
+}
+
+// Options controla cómo se procesan y exportan los archivos.
+type Options struct {
+	// DryRun genera y muestra la interfaz sin escribir CONTABLE.txt ni
+	// guardar el consecutivo o las estadísticas en la base de datos.
+	DryRun bool
+	// PreviewPath es una ruta opcional donde escribir la interfaz en modo
+	// DryRun.
+	PreviewPath string
+	// CSV configura la lectura de los archivos de entrada .csv.
+	CSV CSVOptions
+	// Strict aborta la exportación si algún plan, caja o municipio no tiene
+	// mapeo.
+	Strict bool
+	// RejectionPath es una ruta opcional donde escribir el reporte de
+	// comprobantes descuadrados.
+	RejectionPath string
+	// Operator es quien ejecuta la exportación; se registra en el lote.
+	Operator string
+	// ExportPath es la ruta del archivo de interfaz. Si está vacía se usa
+	// el destino por defecto de config.LoadExport.
+	ExportPath string
+	// Output configura la codificación y los fines de línea de la interfaz.
+	Output OutputOptions
+	// Rounding es la política de redondeo de los montos de los archivos de
+	// entrada. Si está vacía se redondea al peso.
+	Rounding RoundingPolicy
+	// Adjustment configura las líneas de ajuste al peso de las facturas.
+	Adjustment AdjustmentOptions
+}
+
+// buildResult son las líneas generadas a partir de un archivo de entrada.
+type buildResult struct {
+	file string
+	data []MekanoDataStruct
+	// sources relaciona cada comprobante con la fila del archivo que lo generó.
+	sources  map[string]int
+	unmapped *unmappedTracker
+	// receipts son los recibos de pago incluidos en la interfaz.
+	receipts []Receipt
+	// invoices son las facturas incluidas en la interfaz.
+	invoices []Invoice
+	// rows es la cantidad de filas procesadas.
+	rows int
+	// invalid son los montos que no se pudieron leer.
+	invalid []InvalidAmount
+}
+
+// amount lee un monto del archivo de entrada con la política de redondeo
+// configurada. Una celda vacía vale cero; un valor inválido se registra para
+// no exportar la interfaz y también vale cero.
+func (mr *mekanoRepository) amount(b *buildResult, value string, line int, column string) Money {
+	if strings.TrimSpace(value) == "" {
+		return 0
+	}
+	m, err := ParseMoney(value, mr.opts.Rounding)
+	if err != nil {
+		b.invalid = append(b.invalid, InvalidAmount{Row: line, Column: column, Value: value})
+		return 0
+	}
+	return m
+}
+
+// checkAmounts devuelve un InvalidAmountError si el archivo tenía montos
+// inválidos.
+func (b *buildResult) checkAmounts() error {
+	if len(b.invalid) > 0 {
+		return &InvalidAmountError{Amounts: b.invalid}
+	}
+	return nil
 }
 
 type mekanoRepository struct {
-	dr DatabaseRepositoryInterface
+	dr   DatabaseRepositoryInterface
+	m    config.Mappings
+	opts Options
 }
 
-func NewMekanoRepository(dr DatabaseRepositoryInterface) mekanoInterface {
+func NewMekanoRepository(dr DatabaseRepositoryInterface, m config.Mappings, opts Options) mekanoInterface {
+	if opts.Rounding == "" {
+		opts.Rounding = RoundPeso
+	}
+	if opts.Adjustment.Account != "" && opts.Adjustment.Tolerance == 0 {
+		opts.Adjustment.Tolerance = DefaultAdjustmentTolerance
+	}
 
 	return &mekanoRepository{
 		dr,
+		m,
+		opts,
 	}
 }
 
 func (mr *mekanoRepository) Payment(file string) ([]MekanoDataStruct, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
-	var paymentDataSlice []MekanoDataStruct
-	var consecutive, rowCount int = 0, 0
 
-	xlsx, err := excelize.OpenFile(file)
+	payments, err := mr.readPayments(file)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
 
-	excelRows, err := xlsx.GetRows(xlsx.GetSheetName(0))
+	payments, err = mr.skipImported(ctx, payments)
+	if err != nil {
+		return nil, err
+	}
+	if len(payments) == 0 {
+		fmt.Println("No hay pagos nuevos para exportar")
+		return nil, nil
+	}
+
+	// Los recibos se numeran primero con el consecutivo actual para validarlos
+	// y solo después de validarlos se reserva el rango, para no dejar huecos.
+	current, err := mr.dr.GetConsecutive(ctx, SequenceRC)
+	if err != nil {
+		return nil, err
+	}
+
+	p := mr.buildPayment(payments, current)
+
+	if err := p.checkAmounts(); err != nil {
+		return nil, err
+	}
+	if err := mr.checkUnmapped(p.unmapped); err != nil {
+		return nil, err
+	}
+
+	balanceErr := mr.validate(p.data, p.sources)
+
+	if mr.opts.DryRun {
+		s := newPaymentStatistics(file, p.data, current, current+p.rows, mr.m)
+		if err := mr.preview(p.data, s); err != nil {
+			return nil, err
+		}
+		return p.data, balanceErr
+	}
+	if balanceErr != nil {
+		return nil, balanceErr
+	}
+
+	// La reserva queda abierta mientras se escribe la interfaz y solo se
+	// confirma cuando el archivo quedó en su lugar: si la escritura falla, la
+	// secuencia queda como estaba y la numeración no tiene huecos. Mientras
+	// tanto otra ejecución que quiera reservar espera.
+	ctx, cancel = recordContext(len(p.data))
+	defer cancel()
+
+	reservation, err := mr.dr.ReserveConsecutives(ctx, SequenceRC, p.rows)
+	if err != nil {
+		return nil, err
+	}
+	defer reservation.Rollback()
+
+	initialRC, lastRC := reservation.First-1, reservation.First-1+p.rows
+	if initialRC != current {
+		p = mr.buildPayment(payments, initialRC)
+	}
+
+	if err := exporterFile(mr.interfacePath(), p.data, mr.opts.Output); err != nil {
+		return nil, err
+	}
+	if err := reservation.Commit(); err != nil {
+		return nil, fmt.Errorf("la interfaz se escribió con los RC %d-%d pero no se confirmó su reserva, no la importe en Mekano: %w", initialRC+1, lastRC, err)
+	}
+
+	// Aunque falle el lote, los recibos y las estadísticas se registran igual
+	// para no volver a exportar los mismos pagos; los errores se devuelven
+	// juntos al final.
+	batchID, batchErr := mr.saveBatch(ctx, BatchPayment, p.data, file)
+
+	// Si los recibos no quedan registrados, el próximo proceso del mismo
+	// archivo los exportaría otra vez; por eso el error no se ignora.
+	receiptsErr := mr.dr.SaveReceipts(ctx, p.receipts)
+	if receiptsErr != nil {
+		receiptsErr = fmt.Errorf("la interfaz se exportó con los RC %d-%d pero no se registraron los recibos: %w", initialRC+1, lastRC, receiptsErr)
+	}
+
+	statisticsErr := PaymentStatistics(file, p.data, initialRC, lastRC, batchID, ctx, mr.dr, mr.m)
+	return p.data, errors.Join(batchErr, receiptsErr, statisticsErr)
+}
+
+// recordContext devuelve el contexto para reservar los consecutivos de una
+// exportación y registrar lo exportado: el lote, los recibos o facturas y las
+// estadísticas. Su plazo crece con la cantidad de líneas, para que un archivo
+// grande no quede exportado y sin registrar por falta de tiempo.
+func recordContext(lines int) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 30*time.Second+time.Duration(lines)*time.Millisecond)
+}
+
+// readPayments lee las filas del archivo de pagos.
+func (mr *mekanoRepository) readPayments(file string) ([]record, error) {
+	paymentRows, err := openSheet(paymentSchema, file, mr.opts.CSV)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
 	}
+	defer paymentRows.Close()
+
+	var payments []record
+	for paymentRows.Next() {
+		payments = append(payments, paymentRows.Record())
+	}
+	return payments, paymentRows.Err()
+}
+
+// skipImported descarta los pagos cuyo número de recibo ya fue importado en
+// una ejecución anterior o aparece repetido en el mismo archivo, e informa
+// las filas omitidas. Los pagos sin número de recibo no se pueden controlar:
+// se informan y se exportan, pero no se consultan ni se registran.
+func (mr *mekanoRepository) skipImported(ctx context.Context, payments []record) ([]record, error) {
+	numbers := make([]string, 0, len(payments))
+	for _, row := range payments {
+		if number := row.get(colPagoRecibo); number != "" {
+			numbers = append(numbers, number)
+		}
+	}
 
-	c, err := mr.dr.GetPayment(ctx)
+	imported, err := mr.dr.GetReceipts(ctx, numbers)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, row := range excelRows[1:] {
+	seen := map[string]int{}
+	pending := make([]record, 0, len(payments))
+	for _, row := range payments {
+		number := row.get(colPagoRecibo)
+		if number == "" {
+			fmt.Printf("Fila %d sin número de recibo: se exporta sin controlar si ya fue importada\n", row.line)
+			pending = append(pending, row)
+			continue
+		}
+		if r, ok := imported[number]; ok {
+			fmt.Printf("Fila %d omitida: el recibo %s ya fue importado en el RC %d (%s)\n", row.line, number, r.Consecutive, r.CreateAt)
+			continue
+		}
+		if line, ok := seen[number]; ok {
+			fmt.Printf("Fila %d omitida: el recibo %s está repetido en la fila %d\n", row.line, number, line)
+			continue
+		}
+		seen[number] = row.line
+		pending = append(pending, row)
+	}
+	return pending, nil
+}
+
+// buildPayment genera un recibo RC por cada pago, numerados a partir de
+// initialRC+1.
+func (mr *mekanoRepository) buildPayment(payments []record, initialRC int) *buildResult {
+	var paymentDataSlice []MekanoDataStruct
+	var receipts []Receipt
+	sources := map[string]int{}
+	unmapped := newUnmappedTracker()
+	var rowCount int
+	b := &buildResult{}
+
+	for _, row := range payments {
 		rowCount++
-		consecutive = c.Consecutive + rowCount
+		consecutive := initialRC + rowCount
+		sources["RC _ "+strconv.Itoa(consecutive)] = row.line
+		total := mr.amount(b, row.get(colPagoTotal), row.line, colPagoTotal)
+		cero := Money(0).String()
 
 		paymentData := MekanoDataStruct{
 			Tipo:          "RC",
 			Prefijo:       "_",
 			Numero:        strconv.Itoa(consecutive),
 			Secuencia:     "",
-			Fecha:         row[4],
+			Fecha:         row.get(colPagoFecha),
 			Cuenta:        "13050501",
-			Terceros:      row[1],
+			Terceros:      row.get(colPagoDocumento),
 			CentroCostos:  "C1",
 			Nota:          "RECAUDO POR VENTA SERVICIOS",
-			Debito:        "0",
-			Credito:       row[5],
-			Base:          "0",
+			Debito:        cero,
+			Credito:       total.String(),
+			Base:          cero,
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -108,7 +328,7 @@ func (mr *mekanoRepository) Payment(file string) ([]MekanoDataStruct, error) {
 			Signo:         "",
 			CuentaCobrar:  "",
 			CuentaPagar:   "",
-			NombreTercero: row[2],
+			NombreTercero: row.get(colPagoCliente),
 			NombreCentro:  "CENTRO DE COSTOS GENERAL",
 			Interface:     time.Now().Format("02/01/2006 15:04"),
 		}
@@ -119,14 +339,14 @@ func (mr *mekanoRepository) Payment(file string) ([]MekanoDataStruct, error) {
 			Prefijo:       "_",
 			Numero:        strconv.Itoa(consecutive),
 			Secuencia:     "",
-			Fecha:         row[4],
-			Cuenta:        config.Cashier[row[9]],
-			Terceros:      row[1],
+			Fecha:         row.get(colPagoFecha),
+			Cuenta:        unmapped.lookup(config.SectionCashier, mr.m.Cashier, row.get(colPagoCobrador), row.line),
+			Terceros:      row.get(colPagoDocumento),
 			CentroCostos:  "C1",
 			Nota:          "RECAUDO POR VENTA SERVICIOS",
-			Debito:        row[5],
-			Credito:       "0",
-			Base:          "0",
+			Debito:        total.String(),
+			Credito:       cero,
+			Base:          cero,
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -135,361 +355,499 @@ func (mr *mekanoRepository) Payment(file string) ([]MekanoDataStruct, error) {
 			Signo:         "",
 			CuentaCobrar:  "",
 			CuentaPagar:   "",
-			NombreTercero: row[2],
+			NombreTercero: row.get(colPagoCliente),
 			NombreCentro:  "CENTRO DE COSTOS GENERAL",
 			Interface:     time.Now().Format("02/01/2006 15:04"),
 		}
 		paymentDataSlice = append(paymentDataSlice, paymentData2)
+
+		if row.get(colPagoRecibo) == "" {
+			continue
+		}
+		receipts = append(receipts, Receipt{
+			Number:      row.get(colPagoRecibo),
+			Subscriber:  row.get(colPagoAbonado),
+			Amount:      total.String(),
+			PaymentDate: row.get(colPagoFecha),
+			Consecutive: consecutive,
+			CreateAt:    time.Now().Format("2006-01-02"),
+		})
 	}
-	exporterFile(paymentDataSlice)
 
-	PaymentStatistics(file, paymentDataSlice, c.Consecutive, consecutive, ctx, mr.dr)
-	return paymentDataSlice, nil
+	return &buildResult{data: paymentDataSlice, sources: sources, unmapped: unmapped, receipts: receipts, rows: rowCount, invalid: b.invalid}
 }
 
 func (mr *mekanoRepository) Billing(file string, extras string) ([]MekanoDataStruct, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	var montoBaseFinal float64
-	var montoIvaFinal float64
-	var montoDebitoFinal float64
-	var itemIvaBaseFinal float64
-
-	xlsx, err := excelize.OpenFile(file)
+	b, err := mr.buildBilling(file, extras)
 	if err != nil {
-		fmt.Println(err)
 		return nil, err
 	}
+	if len(b.data) == 0 {
+		fmt.Println("No hay facturas nuevas para exportar")
+		return nil, nil
+	}
 
-	billingFile, err := xlsx.GetRows(xlsx.GetSheetName(0))
-	if err != nil {
-		fmt.Println(err)
+	if err := b.checkAmounts(); err != nil {
+		return nil, err
+	}
+	if err := mr.checkUnmapped(b.unmapped); err != nil {
 		return nil, err
 	}
 
-	ivaXlsx, err := excelize.OpenFile(extras)
-	if err != nil {
-		fmt.Println(err)
+	b.data, _ = adjustRounding(b.data, mr.opts.Adjustment)
+	balanceErr := mr.validate(b.data, b.sources)
+
+	if mr.opts.DryRun {
+		if err := mr.preview(b.data, newBillingStatistics(b.data)); err != nil {
+			return nil, err
+		}
+		return b.data, balanceErr
+	}
+	if balanceErr != nil {
+		return nil, balanceErr
+	}
+
+	if err := exporterFile(mr.interfacePath(), b.data, mr.opts.Output); err != nil {
 		return nil, err
 	}
 
-	itemsIvaFile, err := ivaXlsx.GetRows(ivaXlsx.GetSheetName(0))
+	ctx, cancel := recordContext(len(b.data))
+	defer cancel()
+
+	batchID, batchErr := mr.saveBatch(ctx, BatchBilling, b.data, file, extras)
+
+	// Si las facturas no quedan registradas, el próximo proceso del mismo
+	// archivo las exportaría otra vez; por eso el error no se ignora.
+	invoicesErr := mr.dr.SaveInvoices(ctx, b.invoices)
+	if invoicesErr != nil {
+		invoicesErr = fmt.Errorf("la interfaz se exportó pero no se registraron las facturas: %w", invoicesErr)
+	}
+
+	statisticsErr := BillingStatistics(b.data, batchID, mr.dr, ctx, file)
+	return b.data, errors.Join(batchErr, invoicesErr, statisticsErr)
+}
+
+// buildBilling genera las líneas FVE de cada factura del archivo de
+// facturación, usando el archivo de extras para las facturas con varios ítems.
+// Las facturas cuyo CUFE ya fue exportado en un lote anterior, o que aparecen
+// repetidas en el archivo, se omiten y se informan. Las facturas sin CUFE se
+// informan y se exportan, pero no se consultan ni se registran.
+func (mr *mekanoRepository) buildBilling(file string, extras string) (*buildResult, error) {
+	extrasIndex, err := mr.indexExtras(extras)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
 	}
 
-	var BillingDataSheet []MekanoDataStruct
-
+	billingRows, err := openSheet(billingSchema, file, mr.opts.CSV)
 	if err != nil {
-		log.Println(err, "itemsIvaFile")
+		fmt.Println(err)
+		return nil, err
 	}
-
-	for _, bRow := range billingFile[1:] {
-
-		montoDebito, err := strconv.ParseFloat(bRow[14], 64)
-		if err != nil {
-			log.Println(err, "MontoDebito")
-		}
-		_, decimalDebito := math.Modf(montoDebito)
-		if decimalDebito >= 0.5 {
-			montoDebitoFinal = math.Ceil(montoDebito)
-		} else {
-			montoDebitoFinal = math.Round(montoDebito)
+	defer billingRows.Close()
+
+	b := &buildResult{file: file, sources: map[string]int{}, unmapped: newUnmappedTracker()}
+	seen := map[string]int{}
+	chunk := make([]record, 0, inQueryChunk)
+
+	// Las facturas se consultan en la base de datos por bloques para no
+	// cargar el archivo completo en memoria.
+	flush := func() error {
+		cufes := make([]string, 0, len(chunk))
+		for _, bRow := range chunk {
+			if cufe := bRow.get(colFacturaCufe); cufe != "" {
+				cufes = append(cufes, cufe)
+			}
 		}
 
-		montoBase, err := strconv.ParseFloat(bRow[12], 64)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		exported, err := mr.dr.GetInvoices(ctx, cufes)
 		if err != nil {
-			log.Println(err, "MontoBase")
+			return err
 		}
-		_, decimalBase := math.Modf(montoBase)
-		if decimalBase >= 0.5 {
-			montoBaseFinal = math.Ceil(montoBase)
-		} else {
-			montoBaseFinal = math.Round(montoBase)
+
+		for _, bRow := range chunk {
+			cufe := bRow.get(colFacturaCufe)
+			if cufe == "" {
+				fmt.Printf("Fila %d: la factura %s no tiene CUFE, se exporta sin controlar si ya fue exportada\n", bRow.line, bRow.get(colFacturaConsecutivo))
+				mr.appendInvoice(b, bRow, extrasIndex)
+				continue
+			}
+			if inv, ok := exported[cufe]; ok {
+				fmt.Printf("Fila %d omitida: la factura %s ya fue exportada en %s (%s)\n", bRow.line, bRow.get(colFacturaConsecutivo), inv.FileName, inv.CreateAt)
+				continue
+			}
+			if line, ok := seen[cufe]; ok {
+				fmt.Printf("Fila %d omitida: la factura %s está repetida en la fila %d\n", bRow.line, bRow.get(colFacturaConsecutivo), line)
+				continue
+			}
+			seen[cufe] = bRow.line
+			mr.appendInvoice(b, bRow, extrasIndex)
 		}
+		chunk = chunk[:0]
+		return nil
+	}
 
-		montoIva, err := strconv.ParseFloat(strings.TrimSpace(bRow[13]), 64)
-		if err != nil {
-			log.Println(err, "MontoIva")
+	for billingRows.Next() {
+		chunk = append(chunk, billingRows.Record())
+		if len(chunk) == cap(chunk) {
+			if err := flush(); err != nil {
+				return nil, err
+			}
 		}
-		_, decimalIva := math.Modf(montoIva)
+	}
+	if err := billingRows.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
 
-		if decimalIva >= 0.5 {
-			montoIvaFinal = math.Ceil(montoIva)
-		} else {
-			montoIvaFinal = math.Round(montoIva)
+	return b, nil
+}
+
+// appendInvoice agrega a b las líneas de ingreso, IVA y cuenta por cobrar de
+// una factura.
+func (mr *mekanoRepository) appendInvoice(b *buildResult, bRow record, extrasIndex map[extraKey][]string) {
+	b.rows++
+	b.sources["FVE _ "+bRow.get(colFacturaConsecutivo)] = bRow.line
+	centroCostos := b.unmapped.lookup(config.SectionCostCenter, mr.m.CostCenter, unidecode.Unidecode(bRow.get(colFacturaMunicipio)), bRow.line)
+
+	montoDebito := mr.amount(b, bRow.get(colFacturaTotal), bRow.line, colFacturaTotal)
+	montoBase := mr.amount(b, bRow.get(colFacturaBase), bRow.line, colFacturaBase)
+	montoIva := mr.amount(b, bRow.get(colFacturaIva), bRow.line, colFacturaIva)
+	cero := Money(0).String()
+
+	if !strings.Contains(bRow.get(colFacturaItem), ",") {
+		cuenta := b.unmapped.lookup(config.SectionAccounts, mr.m.Accounts, bRow.get(colFacturaItem), bRow.line)
+		billingNormal := MekanoDataStruct{
+			Tipo:          "FVE",
+			Prefijo:       "_",
+			Numero:        bRow.get(colFacturaConsecutivo),
+			Secuencia:     "",
+			Fecha:         bRow.get(colFacturaFecha),
+			Cuenta:        cuenta,
+			Terceros:      bRow.get(colFacturaIdentificacion),
+			CentroCostos:  centroCostos,
+			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+			Debito:        cero,
+			Credito:       montoBase.String(),
+			Base:          cero,
+			Aplica:        "",
+			TipoAnexo:     "",
+			PrefijoAnexo:  "",
+			NumeroAnexo:   "",
+			Usuario:       "SUPERVISOR",
+			Signo:         "",
+			CuentaCobrar:  "",
+			CuentaPagar:   "",
+			NombreTercero: bRow.get(colFacturaCliente),
+			NombreCentro:  bRow.get(colFacturaMunicipio),
+			Interface:     time.Now().Format("02/01/2006 15:04"),
 		}
 
-		if !strings.Contains(bRow[21], ",") {
-			_, ok := config.Accounts[bRow[21]]
-			if !ok {
-				log.Println("Cuenta no existe en la base de datos: ", bRow[21])
-			}
-			billingNormal := MekanoDataStruct{
-				Tipo:          "FVE",
-				Prefijo:       "_",
-				Numero:        bRow[8],
-				Secuencia:     "",
-				Fecha:         bRow[9],
-				Cuenta:        config.Accounts[bRow[21]],
-				Terceros:      bRow[1],
-				CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-				Debito:        "0",
-				Credito:       fmt.Sprintf("%f", montoBaseFinal),
-				Base:          "0",
-				Aplica:        "",
-				TipoAnexo:     "",
-				PrefijoAnexo:  "",
-				NumeroAnexo:   "",
-				Usuario:       "SUPERVISOR",
-				Signo:         "",
-				CuentaCobrar:  "",
-				CuentaPagar:   "",
-				NombreTercero: bRow[2],
-				NombreCentro:  bRow[17],
-				Interface:     time.Now().Format("02/01/2006 15:04"),
-			}
+		b.data = append(b.data, billingNormal)
 
-			BillingDataSheet = append(BillingDataSheet, billingNormal)
-
-			billingIva := MekanoDataStruct{
-				Tipo:          "FVE",
-				Prefijo:       "_",
-				Numero:        bRow[8],
-				Secuencia:     "",
-				Fecha:         bRow[9],
-				Cuenta:        "24080505",
-				Terceros:      bRow[1],
-				CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-				Debito:        "0",
-				Credito:       fmt.Sprintf("%f", montoIvaFinal),
-				Base:          fmt.Sprintf("%f", montoBaseFinal),
-				Aplica:        "",
-				TipoAnexo:     "",
-				PrefijoAnexo:  "",
-				NumeroAnexo:   "",
-				Usuario:       "SUPERVISOR",
-				Signo:         "",
-				CuentaCobrar:  "",
-				CuentaPagar:   "",
-				NombreTercero: bRow[2],
-				NombreCentro:  bRow[17],
-				Interface:     time.Now().Format("02/01/2006 15:04"),
-			}
+		billingIva := MekanoDataStruct{
+			Tipo:          "FVE",
+			Prefijo:       "_",
+			Numero:        bRow.get(colFacturaConsecutivo),
+			Secuencia:     "",
+			Fecha:         bRow.get(colFacturaFecha),
+			Cuenta:        "24080505",
+			Terceros:      bRow.get(colFacturaIdentificacion),
+			CentroCostos:  centroCostos,
+			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+			Debito:        cero,
+			Credito:       montoIva.String(),
+			Base:          montoBase.String(),
+			Aplica:        "",
+			TipoAnexo:     "",
+			PrefijoAnexo:  "",
+			NumeroAnexo:   "",
+			Usuario:       "SUPERVISOR",
+			Signo:         "",
+			CuentaCobrar:  "",
+			CuentaPagar:   "",
+			NombreTercero: bRow.get(colFacturaCliente),
+			NombreCentro:  bRow.get(colFacturaMunicipio),
+			Interface:     time.Now().Format("02/01/2006 15:04"),
+		}
 
-			BillingDataSheet = append(BillingDataSheet, billingIva)
-
-			billingCxC := MekanoDataStruct{
-				Tipo:          "FVE",
-				Prefijo:       "_",
-				Numero:        bRow[8],
-				Secuencia:     "",
-				Fecha:         bRow[9],
-				Cuenta:        "13050501",
-				Terceros:      bRow[1],
-				CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-				Debito:        fmt.Sprintf("%f", montoDebitoFinal),
-				Credito:       "0",
-				Base:          "0",
-				Aplica:        "",
-				TipoAnexo:     "",
-				PrefijoAnexo:  "",
-				NumeroAnexo:   "",
-				Usuario:       "SUPERVISOR",
-				Signo:         "",
-				CuentaCobrar:  "",
-				CuentaPagar:   "",
-				NombreTercero: bRow[2],
-				NombreCentro:  bRow[17],
-				Interface:     time.Now().Format("02/01/2006 15:04"),
-			}
+		b.data = append(b.data, billingIva)
 
-			BillingDataSheet = append(BillingDataSheet, billingCxC)
-		} else {
-			splitBillingItems := strings.Split(bRow[21], ",")
-			for _, item := range splitBillingItems {
-				for _, itemIva := range itemsIvaFile[1:] {
-
-					if itemIva[1] == strings.TrimSpace(item) && itemIva[0] == bRow[0] {
-						itemIvaBase, _ := strconv.ParseFloat(itemIva[2], 64)
-						_, decimalIvaBase := math.Modf(itemIvaBase)
-
-						if decimalIvaBase >= 0.5 {
-							itemIvaBaseFinal = math.Ceil(itemIvaBase)
-						} else {
-							itemIvaBaseFinal = math.Round(itemIvaBase)
-						}
-						_, ok := config.Accounts[unidecode.Unidecode(strings.TrimSpace(item))]
-
-						if !ok {
-							log.Println("Cuenta no existe en la base de datos: ", unidecode.Unidecode(strings.TrimSpace(item)))
-
-						}
-
-						billingNormalPlus := MekanoDataStruct{
-							Tipo:          "FVE",
-							Prefijo:       "_",
-							Numero:        bRow[8],
-							Secuencia:     "",
-							Fecha:         bRow[9],
-							Cuenta:        config.Accounts[unidecode.Unidecode(strings.TrimSpace(item))],
-							Terceros:      bRow[1],
-							CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-							Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-							Debito:        "0",
-							Credito:       fmt.Sprintf("%f", itemIvaBaseFinal),
-							Base:          "0",
-							Aplica:        "",
-							TipoAnexo:     "",
-							PrefijoAnexo:  "",
-							NumeroAnexo:   "",
-							Usuario:       "SUPERVISOR",
-							Signo:         "",
-							CuentaCobrar:  "",
-							CuentaPagar:   "",
-							NombreTercero: bRow[2],
-							NombreCentro:  bRow[17],
-							Interface:     time.Now().Format("02/01/2006 15:04"),
-						}
-						BillingDataSheet = append(BillingDataSheet, billingNormalPlus)
-					}
+		billingCxC := MekanoDataStruct{
+			Tipo:          "FVE",
+			Prefijo:       "_",
+			Numero:        bRow.get(colFacturaConsecutivo),
+			Secuencia:     "",
+			Fecha:         bRow.get(colFacturaFecha),
+			Cuenta:        "13050501",
+			Terceros:      bRow.get(colFacturaIdentificacion),
+			CentroCostos:  centroCostos,
+			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+			Debito:        montoDebito.String(),
+			Credito:       cero,
+			Base:          cero,
+			Aplica:        "",
+			TipoAnexo:     "",
+			PrefijoAnexo:  "",
+			NumeroAnexo:   "",
+			Usuario:       "SUPERVISOR",
+			Signo:         "",
+			CuentaCobrar:  "",
+			CuentaPagar:   "",
+			NombreTercero: bRow.get(colFacturaCliente),
+			NombreCentro:  bRow.get(colFacturaMunicipio),
+			Interface:     time.Now().Format("02/01/2006 15:04"),
+		}
+
+		b.data = append(b.data, billingCxC)
+	} else {
+		splitBillingItems := strings.Split(bRow.get(colFacturaItem), ",")
+		for _, item := range splitBillingItems {
+			for _, base := range extrasIndex[extraKey{abonado: bRow.get(colFacturaAbonado), item: strings.TrimSpace(item)}] {
+				itemBase := mr.amount(b, base, bRow.line, colExtraBase)
+				cuenta := b.unmapped.lookup(config.SectionAccounts, mr.m.Accounts, unidecode.Unidecode(strings.TrimSpace(item)), bRow.line)
+
+				billingNormalPlus := MekanoDataStruct{
+					Tipo:          "FVE",
+					Prefijo:       "_",
+					Numero:        bRow.get(colFacturaConsecutivo),
+					Secuencia:     "",
+					Fecha:         bRow.get(colFacturaFecha),
+					Cuenta:        cuenta,
+					Terceros:      bRow.get(colFacturaIdentificacion),
+					CentroCostos:  centroCostos,
+					Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+					Debito:        cero,
+					Credito:       itemBase.String(),
+					Base:          cero,
+					Aplica:        "",
+					TipoAnexo:     "",
+					PrefijoAnexo:  "",
+					NumeroAnexo:   "",
+					Usuario:       "SUPERVISOR",
+					Signo:         "",
+					CuentaCobrar:  "",
+					CuentaPagar:   "",
+					NombreTercero: bRow.get(colFacturaCliente),
+					NombreCentro:  bRow.get(colFacturaMunicipio),
+					Interface:     time.Now().Format("02/01/2006 15:04"),
 				}
+				b.data = append(b.data, billingNormalPlus)
 			}
-			billingIvaPlus := MekanoDataStruct{
-				Tipo:          "FVE",
-				Prefijo:       "_",
-				Numero:        bRow[8],
-				Secuencia:     "",
-				Fecha:         bRow[9],
-				Cuenta:        "24080505",
-				Terceros:      bRow[1],
-				CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-				Debito:        "0",
-				Credito:       fmt.Sprintf("%f", montoIvaFinal),
-				Base:          fmt.Sprintf("%f", montoBaseFinal),
-				Aplica:        "",
-				TipoAnexo:     "",
-				PrefijoAnexo:  "",
-				NumeroAnexo:   "",
-				Usuario:       "SUPERVISOR",
-				Signo:         "",
-				CuentaCobrar:  "",
-				CuentaPagar:   "",
-				NombreTercero: bRow[2],
-				NombreCentro:  bRow[17],
-				Interface:     time.Now().Format("02/01/2006 15:04"),
-			}
+		}
+		billingIvaPlus := MekanoDataStruct{
+			Tipo:          "FVE",
+			Prefijo:       "_",
+			Numero:        bRow.get(colFacturaConsecutivo),
+			Secuencia:     "",
+			Fecha:         bRow.get(colFacturaFecha),
+			Cuenta:        "24080505",
+			Terceros:      bRow.get(colFacturaIdentificacion),
+			CentroCostos:  centroCostos,
+			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+			Debito:        cero,
+			Credito:       montoIva.String(),
+			Base:          montoBase.String(),
+			Aplica:        "",
+			TipoAnexo:     "",
+			PrefijoAnexo:  "",
+			NumeroAnexo:   "",
+			Usuario:       "SUPERVISOR",
+			Signo:         "",
+			CuentaCobrar:  "",
+			CuentaPagar:   "",
+			NombreTercero: bRow.get(colFacturaCliente),
+			NombreCentro:  bRow.get(colFacturaMunicipio),
+			Interface:     time.Now().Format("02/01/2006 15:04"),
+		}
 
-			BillingDataSheet = append(BillingDataSheet, billingIvaPlus)
-
-			billingCxCPlus := MekanoDataStruct{
-				Tipo:          "FVE",
-				Prefijo:       "_",
-				Numero:        bRow[8],
-				Secuencia:     "",
-				Fecha:         bRow[9],
-				Cuenta:        "13050501",
-				Terceros:      bRow[1],
-				CentroCostos:  config.CostCenter[unidecode.Unidecode(bRow[17])],
-				Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-				Debito:        fmt.Sprintf("%f", montoDebitoFinal),
-				Credito:       "0",
-				Base:          "0",
-				Aplica:        "",
-				TipoAnexo:     "",
-				PrefijoAnexo:  "",
-				NumeroAnexo:   "",
-				Usuario:       "SUPERVISOR",
-				Signo:         "",
-				CuentaCobrar:  "",
-				CuentaPagar:   "",
-				NombreTercero: bRow[2],
-				NombreCentro:  bRow[17],
-				Interface:     time.Now().Format("02/01/2006 15:04"),
-			}
+		b.data = append(b.data, billingIvaPlus)
 
-			BillingDataSheet = append(BillingDataSheet, billingCxCPlus)
+		billingCxCPlus := MekanoDataStruct{
+			Tipo:          "FVE",
+			Prefijo:       "_",
+			Numero:        bRow.get(colFacturaConsecutivo),
+			Secuencia:     "",
+			Fecha:         bRow.get(colFacturaFecha),
+			Cuenta:        "13050501",
+			Terceros:      bRow.get(colFacturaIdentificacion),
+			CentroCostos:  centroCostos,
+			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
+			Debito:        montoDebito.String(),
+			Credito:       cero,
+			Base:          cero,
+			Aplica:        "",
+			TipoAnexo:     "",
+			PrefijoAnexo:  "",
+			NumeroAnexo:   "",
+			Usuario:       "SUPERVISOR",
+			Signo:         "",
+			CuentaCobrar:  "",
+			CuentaPagar:   "",
+			NombreTercero: bRow.get(colFacturaCliente),
+			NombreCentro:  bRow.get(colFacturaMunicipio),
+			Interface:     time.Now().Format("02/01/2006 15:04"),
 		}
+
+		b.data = append(b.data, billingCxCPlus)
+	}
+
+	if bRow.get(colFacturaCufe) == "" {
+		return
 	}
+	b.invoices = append(b.invoices, Invoice{
+		Cufe:       bRow.get(colFacturaCufe),
+		Number:     bRow.get(colFacturaConsecutivo),
+		Subscriber: bRow.get(colFacturaAbonado),
+		FileName:   b.file,
+		CreateAt:   time.Now().Format("2006-01-02"),
+	})
+}
 
-	exporterFile(BillingDataSheet)
-	BillingStatistics(BillingDataSheet, mr.dr, ctx, file)
-	return BillingDataSheet, nil
+// extraKey identifica los ítems del archivo de extras por abonado y nombre.
+type extraKey struct {
+	abonado string
+	item    string
 }
 
-func exporterFile(mekanoData []MekanoDataStruct) {
-	txtFile, err := os.Create(filepath.Join(config.MekanoExportPath, "CONTABLE.txt"))
+// indexExtras lee una sola vez el archivo de extras y agrupa las bases de
+// cada ítem por abonado.
+func (mr *mekanoRepository) indexExtras(extras string) (map[extraKey][]string, error) {
+	extrasRows, err := openSheet(extrasSchema, extras, mr.opts.CSV)
 	if err != nil {
-		fmt.Println(err)
-		return
+		return nil, err
+	}
+	defer extrasRows.Close()
+
+	index := map[extraKey][]string{}
+	for extrasRows.Next() {
+		r := extrasRows.Record()
+		key := extraKey{abonado: r.get(colExtraAbonado), item: r.get(colExtraItem)}
+		index[key] = append(index[key], r.get(colExtraBase))
+	}
+	return index, extrasRows.Err()
+}
+
+// checkUnmapped informa los valores sin mapeo, sugiriendo para los ítems
+// facturados las cuentas con nombres parecidos. En modo estricto devuelve un
+// UnmappedError para que no se exporte la interfaz.
+func (mr *mekanoRepository) checkUnmapped(t *unmappedTracker) error {
+	if len(t.keys) == 0 {
+		return nil
 	}
-	defer txtFile.Close()
-
-	writer := csv.NewWriter(txtFile)
-	writer.Comma = ','
-
-	for _, data := range mekanoData {
-		row := []string{
-			data.Tipo,
-			data.Prefijo,
-			data.Numero,
-			data.Secuencia,
-			data.Fecha,
-			data.Cuenta,
-			data.Terceros,
-			data.CentroCostos,
-			data.Nota,
-			data.Debito,
-			data.Credito,
-			data.Base,
-			data.Aplica,
-			data.TipoAnexo,
-			data.PrefijoAnexo,
-			data.NumeroAnexo,
-			data.Usuario,
-			data.Signo,
-			data.CuentaCobrar,
-			data.CuentaPagar,
-			data.NombreTercero,
-			data.NombreCentro,
-			data.Interface,
+	for i, k := range t.keys {
+		if k.Section == config.SectionAccounts {
+			t.keys[i].Suggestions = suggest(k.Key, mr.m.Accounts, maxSuggestions)
 		}
-		writer.Write(row)
 	}
-	writer.Flush()
+	if mr.opts.Strict {
+		return &UnmappedError{Keys: t.keys}
+	}
+	log.Printf("Valores sin mapeo:\n%s", unmappedReport(t.keys))
+	return nil
+}
+
+// validate verifica que cada comprobante cuadre y, si no, escribe el reporte
+// de rechazos cuando se configuró RejectionPath.
+func (mr *mekanoRepository) validate(data []MekanoDataStruct, sources map[string]int) error {
+	err := checkBalance(data, sources)
+	if unbalanced, ok := err.(*UnbalancedError); ok && mr.opts.RejectionPath != "" {
+		if err := writeRejectionReport(mr.opts.RejectionPath, unbalanced); err != nil {
+			log.Println(err)
+		}
+	}
+	return err
+}
+
+// interfaceRow devuelve los campos de una línea en el orden de la interfaz.
+func interfaceRow(data MekanoDataStruct) []string {
+	return []string{
+		data.Tipo,
+		data.Prefijo,
+		data.Numero,
+		data.Secuencia,
+		data.Fecha,
+		data.Cuenta,
+		data.Terceros,
+		data.CentroCostos,
+		data.Nota,
+		data.Debito,
+		data.Credito,
+		data.Base,
+		data.Aplica,
+		data.TipoAnexo,
+		data.PrefijoAnexo,
+		data.NumeroAnexo,
+		data.Usuario,
+		data.Signo,
+		data.CuentaCobrar,
+		data.CuentaPagar,
+		data.NombreTercero,
+		data.NombreCentro,
+		data.Interface,
+	}
 }
 
 type paymentStatistics struct {
 	FileName    string `json:"archivo"`
 	RangoRC     string `json:"rango-rc"`
-	Bancolombia int    `json:"bancolombia"`
-	Davivienda  int    `json:"davivienda"`
-	Susuerte    int    `json:"susuerte"`
-	PayU        int    `json:"payu"`
-	Efectivo    int    `json:"efectivo"`
-	Total       int    `json:"total"`
+	Filas       int    `json:"filas"`
+	Bancolombia Money  `json:"bancolombia"`
+	Davivienda  Money  `json:"davivienda"`
+	Susuerte    Money  `json:"susuerte"`
+	PayU        Money  `json:"payu"`
+	Efectivo    Money  `json:"efectivo"`
+	Total       Money  `json:"total"`
 }
 type billingStatistics struct {
-	Debito  float64 `json:"debito"`
-	Credito float64 `json:"credito"`
-	Base    float64 `json:"base"`
+	Debito  Money `json:"debito"`
+	Credito Money `json:"credito"`
+	Base    Money `json:"base"`
 }
 
-func PaymentStatistics(fileName string, data []MekanoDataStruct, initialRC, lastRC int, ctx context.Context, dr DatabaseRepositoryInterface) {
+// PaymentStatistics registra la ejecución de pagos en el historial y muestra
+// sus totales. Devuelve el error si no se pudo registrar.
+func PaymentStatistics(fileName string, data []MekanoDataStruct, initialRC, lastRC int, batchID int64, ctx context.Context, dr DatabaseRepositoryInterface, m config.Mappings) error {
 
-	var efectivo, bancolombia, davivienda, susuerte, payU, total int = 0, 0, 0, 0, 0, 0
+	s := newPaymentStatistics(fileName, data, initialRC, lastRC, m)
+
+	result, err := json.MarshalIndent(s, "", " ")
+	if err != nil {
+		log.Println(err)
+	}
+
+	err = dr.SavePayment(ctx, Payment{
+		Consecutive: lastRC,
+		CreateAt:    time.Now().Format("2006-01-02"),
+		FileName:    fileName,
+		FirstRC:     initialRC + 1,
+		BatchID:     batchID,
+		Rows:        s.Filas,
+		Bancolombia: s.Bancolombia,
+		Davivienda:  s.Davivienda,
+		Susuerte:    s.Susuerte,
+		PayU:        s.PayU,
+		Cash:        s.Efectivo,
+		Total:       s.Total,
+	})
+	if err != nil {
+		return fmt.Errorf("la interfaz se exportó con los RC %d-%d pero no se registró la ejecución en el historial: %w", initialRC+1, lastRC, err)
+	}
+	log.Println(string(result))
+	return nil
+}
+
+func newPaymentStatistics(fileName string, data []MekanoDataStruct, initialRC, lastRC int, m config.Mappings) paymentStatistics {
+
+	var efectivo, bancolombia, davivienda, susuerte, payU, total Money
 
 	for _, d := range data {
-		debito, err := strconv.Atoi(d.Debito)
+		debito := lineAmount(d.Debito)
 		total += debito
-		if err != nil {
-			log.Println(err)
-		}
 		switch d.Cuenta {
 		case "11050501": //Efectivo
 			efectivo += debito
@@ -497,16 +855,17 @@ func PaymentStatistics(fileName string, data []MekanoDataStruct, initialRC, last
 			bancolombia += debito
 		case "11200510": //Davivienda
 			davivienda += debito
-		case config.Cashier["SUSUERTE S"]: //Pay U
+		case m.Cashier["SUSUERTE S"]: //Pay U
 			susuerte += debito
-		case config.Cashier["PAY U"]: //Susuerte
+		case m.Cashier["PAY U"]: //Susuerte
 			payU += debito
 		}
 	}
 
-	s := paymentStatistics{
+	return paymentStatistics{
 		FileName:    fileName,
 		RangoRC:     fmt.Sprintf("%d-%d", initialRC+1, lastRC),
+		Filas:       lastRC - initialRC,
 		Efectivo:    efectivo,
 		Bancolombia: bancolombia,
 		Davivienda:  davivienda,
@@ -514,51 +873,40 @@ func PaymentStatistics(fileName string, data []MekanoDataStruct, initialRC, last
 		Susuerte:    susuerte,
 		Total:       total,
 	}
+}
 
-	result, err := json.MarshalIndent(s, "", " ")
+// BillingStatistics registra la ejecución de facturación en el historial y
+// muestra sus totales. Devuelve el error si no se pudo registrar.
+func BillingStatistics(data []MekanoDataStruct, batchID int64, dr DatabaseRepositoryInterface, ctx context.Context, fileName string) error {
+
+	bs := newBillingStatistics(data)
+
+	result, err := json.Marshal(bs)
 	if err != nil {
 		log.Println(err)
 	}
 
-	err = dr.SavePayment(ctx, Payment{Consecutive: lastRC, CreateAt: time.Now().Format("2006-01-02"), FileName: fileName})
+	err = dr.SaveBilling(ctx, Billing{Debit: bs.Debito, Credit: bs.Credito, Base: bs.Base, FileName: fileName, CreateAt: time.Now().Format("2006-01-02"), BatchID: batchID})
 	if err != nil {
-		log.Println(err)
+		return fmt.Errorf("la interfaz se exportó pero no se registró la ejecución en el historial: %w", err)
 	}
-	log.Println(string(result))
 
+	log.Println(string(result))
+	return nil
 }
 
-var (
-	d, c, b float64 = 0, 0, 0
-)
-
-func BillingStatistics(data []MekanoDataStruct, dr DatabaseRepositoryInterface, ctx context.Context, fileName string) {
+func newBillingStatistics(data []MekanoDataStruct) billingStatistics {
+	var d, c, b Money
 
 	for _, row := range data {
-		debito, _ := strconv.ParseFloat(row.Debito, 64)
-		d += debito
-		credito, _ := strconv.ParseFloat(row.Credito, 64)
-		c += credito
-		base, _ := strconv.ParseFloat(row.Base, 64)
-		b += base
+		d += lineAmount(row.Debito)
+		c += lineAmount(row.Credito)
+		b += lineAmount(row.Base)
 	}
 
-	bs := billingStatistics{
+	return billingStatistics{
 		Debito:  d,
 		Credito: c,
 		Base:    b,
 	}
-
-	result, err := json.Marshal(bs)
-	if err != nil {
-		log.Println(err)
-	}
-
-	err = dr.SaveBilling(ctx, Billing{Debit: int(d), Credit: int(c), Base: int(b), FileName: fileName, CreateAt: time.Now().Format("2006-01-02")})
-	if err != nil {
-		log.Println(err)
-	}
-
-	log.Println(string(result))
-
 }
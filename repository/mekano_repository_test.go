@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/csv"
+	"errors"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -23,7 +24,7 @@ func TestMekanoPayment(t *testing.T) {
 		t.Fatalf("Error al inicial la base de datos: %v", err)
 	}
 
-	mekano := NewMekanoRepository(dr)
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{})
 	paymentData, err := mekano.Payment(file)
 	if err != nil {
 		if err != nil {
@@ -57,9 +58,9 @@ func TestMekanoPayment(t *testing.T) {
 			Terceros:      "1060536367",
 			CentroCostos:  "C1",
 			Nota:          "RECAUDO POR VENTA SERVICIOS",
-			Debito:        "0",
-			Credito:       "75000",
-			Base:          "0",
+			Debito:        "0.00",
+			Credito:       "75000.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -82,9 +83,9 @@ func TestMekanoPayment(t *testing.T) {
 			Terceros:      "1060536367",
 			CentroCostos:  "C1",
 			Nota:          "RECAUDO POR VENTA SERVICIOS",
-			Debito:        "75000",
-			Credito:       "0",
-			Base:          "0",
+			Debito:        "75000.00",
+			Credito:       "0.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -122,7 +123,7 @@ func TestMekanoBilling(t *testing.T) {
 		t.Fatalf("Error al iniciar la base de datos: %v", err)
 	}
 
-	mekano := NewMekanoRepository(dr)
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{})
 
 	billingData, err := mekano.Billing(file, extras)
 	if err != nil {
@@ -149,9 +150,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "159122542",
 			CentroCostos:  "101",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "0",
-			Credito:       "63950.000000",
-			Base:          "0",
+			Debito:        "0.00",
+			Credito:       "63950.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -175,9 +176,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "159122542",
 			CentroCostos:  "101",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "0",
-			Credito:       "23025.000000",
-			Base:          "0",
+			Debito:        "0.00",
+			Credito:       "23025.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -200,9 +201,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "159122542",
 			CentroCostos:  "101",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "0",
-			Credito:       "16525.000000",
-			Base:          "86975.000000",
+			Debito:        "0.00",
+			Credito:       "16525.00",
+			Base:          "86975.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -225,9 +226,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "159122542",
 			CentroCostos:  "101",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "103500.000000",
-			Credito:       "0",
-			Base:          "0",
+			Debito:        "103500.00",
+			Credito:       "0.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -251,9 +252,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "797339211",
 			CentroCostos:  "102",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "0",
-			Credito:       "63025.000000",
-			Base:          "0",
+			Debito:        "0.00",
+			Credito:       "63025.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -276,9 +277,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "797339211",
 			CentroCostos:  "102",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "0",
-			Credito:       "11975.000000",
-			Base:          "63025.000000",
+			Debito:        "0.00",
+			Credito:       "11975.00",
+			Base:          "63025.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -301,9 +302,9 @@ func TestMekanoBilling(t *testing.T) {
 			Terceros:      "797339211",
 			CentroCostos:  "102",
 			Nota:          "FACTURA ELECTRÓNICA DE VENTA",
-			Debito:        "75000.000000",
-			Credito:       "0",
-			Base:          "0",
+			Debito:        "75000.00",
+			Credito:       "0.00",
+			Base:          "0.00",
 			Aplica:        "",
 			TipoAnexo:     "",
 			PrefijoAnexo:  "",
@@ -329,12 +330,14 @@ func TestMekanoExporterFile(t *testing.T) {
 	}
 
 	// Directorio temporal para el archivo de prueba
+	filePath := filepath.Join(t.TempDir(), "CONTABLE.txt")
 
 	// Ejecutar la función de prueba
-	exporterFile(mekanoData)
+	if err := exporterFile(filePath, mekanoData, OutputOptions{}); err != nil {
+		t.Fatalf("Error al exportar la interfaz: %v", err)
+	}
 
 	// Comprobar si el archivo ha sido creado
-	filePath := filepath.Join(config.MekanoExportPath, "CONTABLE.txt")
 	_, err := os.Stat(filePath)
 	if err != nil {
 		t.Fatalf("No se pudo encontrar el archivo CONTABLE.txt: %v", err)
@@ -398,6 +401,68 @@ func TestMekanoExporterFile(t *testing.T) {
 	}
 }
 
+func TestMekanoExporterFileBackup(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "CONTABLE.txt")
+
+	first := []MekanoDataStruct{{Tipo: "RC", Numero: "15001"}}
+	second := []MekanoDataStruct{{Tipo: "RC", Numero: "15002"}}
+	if err := exporterFile(filePath, first, OutputOptions{}); err != nil {
+		t.Fatalf("Error al exportar la interfaz: %v", err)
+	}
+	if err := exporterFile(filePath, second, OutputOptions{}); err != nil {
+		t.Fatalf("Error al exportar la interfaz: %v", err)
+	}
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "CONTABLE.*.txt"))
+	if len(backups) != 1 {
+		t.Fatalf("Se esperaba un respaldo de la interfaz anterior, se obtuvo: %v", backups)
+	}
+	previous, _ := os.ReadFile(backups[0])
+	current, _ := os.ReadFile(filePath)
+	if !strings.Contains(string(previous), "15001") || !strings.Contains(string(current), "15002") {
+		t.Errorf("Contenido inesperado:\nrespaldo: %s\nactual: %s", previous, current)
+	}
+
+	// No deben quedar archivos temporales.
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("Quedaron archivos temporales: %v", tmp)
+	}
+
+	if err := exporterFile(filepath.Join(dir, "no-existe", "CONTABLE.txt"), first, OutputOptions{}); err == nil {
+		t.Error("Se esperaba un error al exportar a un directorio inexistente")
+	}
+}
+
+func TestMekanoExporterFileEncoding(t *testing.T) {
+	data := []MekanoDataStruct{{Tipo: "FVE", Nota: "FACTURA ELECTRÓNICA DE VENTA", NombreTercero: "PEÑA ŁUKASZ"}}
+
+	tests := []struct {
+		opts     OutputOptions
+		expected string
+	}{
+		{OutputOptions{}, "FVE,,,,,,,,FACTURA ELECTRÓNICA DE VENTA,,,,,,,,,,,,PEÑA ŁUKASZ,,\n"},
+		{OutputOptions{Encoding: "utf-8-bom"}, "\uFEFFFVE,,,,,,,,FACTURA ELECTRÓNICA DE VENTA,,,,,,,,,,,,PEÑA ŁUKASZ,,\n"},
+		{OutputOptions{Encoding: "windows-1252", CRLF: true}, "FVE,,,,,,,,FACTURA ELECTR\xd3NICA DE VENTA,,,,,,,,,,,,PE\xd1A LUKASZ,,\r\n"},
+		{OutputOptions{Encoding: "iso-8859-1"}, "FVE,,,,,,,,FACTURA ELECTR\xd3NICA DE VENTA,,,,,,,,,,,,PE\xd1A LUKASZ,,\n"},
+	}
+
+	for _, tt := range tests {
+		filePath := filepath.Join(t.TempDir(), "CONTABLE.txt")
+		if err := exporterFile(filePath, data, tt.opts); err != nil {
+			t.Fatalf("%+v: error al exportar la interfaz: %v", tt.opts, err)
+		}
+		content, _ := os.ReadFile(filePath)
+		if string(content) != tt.expected {
+			t.Errorf("%+v: se esperaba %q, se obtuvo %q", tt.opts, tt.expected, content)
+		}
+	}
+
+	if err := (OutputOptions{Encoding: "ebcdic"}).Validate(); err == nil {
+		t.Error("Se esperaba un error por una codificación no soportada")
+	}
+}
+
 func TestPaymentStatistics(t *testing.T) {
 
 }
@@ -405,3 +470,527 @@ func TestPaymentStatistics(t *testing.T) {
 func TestBillingStatistics(t *testing.T) {
 
 }
+
+// testExportPath devuelve una ruta temporal para el archivo de interfaz.
+func testExportPath(t *testing.T) string {
+	t.Helper()
+	return filepath.Join(t.TempDir(), "CONTABLE.txt")
+}
+
+// fakeDatabaseRepository registra las llamadas para verificar qué se
+// persiste sin necesidad de un servidor MySQL.
+type fakeDatabaseRepository struct {
+	payment     Payment
+	consecutive int
+	payments    []Payment
+	billings    []Billing
+	receipts    map[string]Receipt
+	invoices    map[string]Invoice
+	batches     []Batch
+}
+
+func (f *fakeDatabaseRepository) GetPayment(ctx context.Context) (Payment, error) {
+	return f.payment, nil
+}
+
+func (f *fakeDatabaseRepository) SavePayment(ctx context.Context, payment Payment) error {
+	f.payments = append(f.payments, payment)
+	return nil
+}
+
+func (f *fakeDatabaseRepository) SaveBilling(ctx context.Context, billing Billing) error {
+	f.billings = append(f.billings, billing)
+	return nil
+}
+
+func (f *fakeDatabaseRepository) GetMappings(ctx context.Context) (config.Mappings, error) {
+	return config.Mappings{}, nil
+}
+
+func (f *fakeDatabaseRepository) SaveMappings(ctx context.Context, mappings config.Mappings) error {
+	return nil
+}
+
+func (f *fakeDatabaseRepository) DeleteMapping(ctx context.Context, section, name string) error {
+	return nil
+}
+
+func (f *fakeDatabaseRepository) GetReceipts(ctx context.Context, numbers []string) (map[string]Receipt, error) {
+	receipts := map[string]Receipt{}
+	for _, n := range numbers {
+		if r, ok := f.receipts[n]; ok {
+			receipts[n] = r
+		}
+	}
+	return receipts, nil
+}
+
+func (f *fakeDatabaseRepository) SaveReceipts(ctx context.Context, receipts []Receipt) error {
+	if f.receipts == nil {
+		f.receipts = map[string]Receipt{}
+	}
+	for _, r := range receipts {
+		f.receipts[r.Number] = r
+	}
+	return nil
+}
+
+func (f *fakeDatabaseRepository) GetInvoices(ctx context.Context, cufes []string) (map[string]Invoice, error) {
+	invoices := map[string]Invoice{}
+	for _, c := range cufes {
+		if i, ok := f.invoices[c]; ok {
+			invoices[c] = i
+		}
+	}
+	return invoices, nil
+}
+
+func (f *fakeDatabaseRepository) SaveInvoices(ctx context.Context, invoices []Invoice) error {
+	if f.invoices == nil {
+		f.invoices = map[string]Invoice{}
+	}
+	for _, i := range invoices {
+		f.invoices[i.Cufe] = i
+	}
+	return nil
+}
+
+func (f *fakeDatabaseRepository) GetConsecutive(ctx context.Context, sequence string) (int, error) {
+	return f.consecutive, nil
+}
+
+func (f *fakeDatabaseRepository) ReserveConsecutives(ctx context.Context, sequence string, n int) (*Reservation, error) {
+	return &Reservation{
+		First:    f.consecutive + 1,
+		commit:   func() error { f.consecutive += n; return nil },
+		rollback: func() error { return nil },
+	}, nil
+}
+
+func (f *fakeDatabaseRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return nil, nil
+}
+
+func (f *fakeDatabaseRepository) MigrateUp(ctx context.Context, steps int) ([]Migration, error) {
+	return nil, nil
+}
+
+func (f *fakeDatabaseRepository) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	return nil, nil
+}
+
+func (f *fakeDatabaseRepository) SeedConsecutive(ctx context.Context, sequence string, first int) error {
+	f.consecutive = first - 1
+	return nil
+}
+
+func (f *fakeDatabaseRepository) ListPayments(ctx context.Context, filter HistoryFilter) ([]Payment, error) {
+	return f.payments, nil
+}
+
+func (f *fakeDatabaseRepository) ListBillings(ctx context.Context, filter HistoryFilter) ([]Billing, error) {
+	return f.billings, nil
+}
+
+func (f *fakeDatabaseRepository) SaveBatch(ctx context.Context, batch Batch) (int64, error) {
+	batch.ID = int64(len(f.batches) + 1)
+	f.batches = append(f.batches, batch)
+	return batch.ID, nil
+}
+
+func (f *fakeDatabaseRepository) GetBatch(ctx context.Context, id int64) (Batch, error) {
+	for _, batch := range f.batches {
+		if batch.ID == id {
+			return batch, nil
+		}
+	}
+	return Batch{}, ErrBatchNotFound
+}
+
+func TestMekanoDryRun(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	previewPath := filepath.Join(t.TempDir(), "PREVIEW.txt")
+
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{DryRun: true, PreviewPath: previewPath})
+
+	paymentData, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if len(paymentData) != 2 || paymentData[0].Numero != "15001" {
+		t.Errorf("Se esperaban 2 líneas con el RC 15001, se obtuvo: %+v", paymentData)
+	}
+
+	_, err = mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturacion: %v", err)
+	}
+
+	if len(dr.payments) != 0 || len(dr.billings) != 0 || dr.consecutive != 15000 {
+		t.Errorf("El modo de prueba no debe guardar en la base de datos: %+v %+v", dr.payments, dr.billings)
+	}
+
+	if _, err := os.Stat(previewPath); err != nil {
+		t.Errorf("No se escribió la vista previa: %v", err)
+	}
+}
+
+func TestMekanoStrict(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+
+	mappings := config.DefaultMappings()
+	delete(mappings.Cashier, "SUSUERTE S")
+	delete(mappings.Accounts, "F.O. COMERCIAL BASICO.")
+
+	mekano := NewMekanoRepository(dr, mappings, Options{Strict: true})
+
+	_, err := mekano.Payment("../test_files/payment_test.xlsx")
+	var unmapped *UnmappedError
+	if !errors.As(err, &unmapped) {
+		t.Fatalf("Se esperaba un UnmappedError, se obtuvo: %v", err)
+	}
+	if len(unmapped.Keys) != 1 || unmapped.Keys[0].Key != "SUSUERTE S" || unmapped.Keys[0].Rows[0] != 2 {
+		t.Errorf("Reporte inesperado: %+v", unmapped.Keys)
+	}
+
+	_, err = mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if !errors.As(err, &unmapped) {
+		t.Fatalf("Se esperaba un UnmappedError, se obtuvo: %v", err)
+	}
+	if len(unmapped.Keys) != 1 || unmapped.Keys[0].Count != 2 {
+		t.Errorf("Reporte inesperado: %+v", unmapped.Keys)
+	}
+
+	if len(dr.payments) != 0 || len(dr.billings) != 0 {
+		t.Errorf("El modo estricto no debe guardar en la base de datos")
+	}
+}
+
+func TestMekanoPaymentSkipsImportedReceipts(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	paymentData, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if len(paymentData) != 2 {
+		t.Fatalf("Se esperaban 2 líneas, se obtuvieron %d", len(paymentData))
+	}
+
+	r, ok := dr.receipts["107376"]
+	if !ok || r.Consecutive != 15001 || r.Subscriber != "5449" || r.Amount != "75000.00" {
+		t.Errorf("Recibo registrado inesperado: %+v", r)
+	}
+
+	paymentData, err = mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if len(paymentData) != 0 || len(dr.payments) != 1 {
+		t.Errorf("El segundo proceso no debe exportar recibos ya importados: %+v", paymentData)
+	}
+}
+
+func TestMekanoPaymentWithoutReceiptNumber(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pagos.csv")
+	content := "N° Abonado,Documento,Cliente,Nro Recibo,Fecha,Total Pago,Cobrador\n" +
+		"5449,1058845404,CLIENTE UNO,,27/06/2023,75000,SUSUERTE S\n" +
+		"5450,1058845405,CLIENTE DOS,107376,27/06/2023,75000,SUSUERTE S\n" +
+		"5451,1058845406,CLIENTE TRES,,27/06/2023,75000,SUSUERTE S\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	paymentData, err := mekano.Payment(path)
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if len(paymentData) != 6 {
+		t.Fatalf("Se esperaban 6 líneas, se obtuvieron %d", len(paymentData))
+	}
+	if _, ok := dr.receipts[""]; ok || len(dr.receipts) != 1 {
+		t.Errorf("Solo debe registrarse el recibo con número: %+v", dr.receipts)
+	}
+
+	paymentData, err = mekano.Payment(path)
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+	if len(paymentData) != 4 {
+		t.Errorf("Los pagos sin número de recibo no deben omitirse: se obtuvieron %d líneas", len(paymentData))
+	}
+}
+
+func TestMekanoBillingSkipsExportedInvoices(t *testing.T) {
+	dr := &fakeDatabaseRepository{
+		invoices: map[string]Invoice{
+			"332b2f99dc1bbc73f60e4ad198195c383aa179b7f1e05c5b483a23fc42323c6c54ab692291021b5b61c15ae872ff4adb": {Number: "66137"},
+		},
+	}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	billingData, err := mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturacion: %v", err)
+	}
+
+	for _, d := range billingData {
+		if d.Numero == "66137" {
+			t.Fatalf("La factura 66137 ya fue exportada y no debe incluirse")
+		}
+	}
+	if len(billingData) != 3 {
+		t.Errorf("Se esperaban 3 líneas de la factura 66138, se obtuvieron %d", len(billingData))
+	}
+
+	billingData, err = mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturacion: %v", err)
+	}
+	if len(billingData) != 0 || len(dr.billings) != 1 {
+		t.Errorf("El segundo proceso no debe exportar facturas ya exportadas: %+v", billingData)
+	}
+}
+
+func TestMekanoBillingWithoutCufe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "facturacion.csv")
+	content := "Nro Abonado,Identificación,Cliente,Consecutivo,Fecha Emisión,Monto Base,Monto IVA,Monto Total,Cufe,Municipio,Item Facturado\n" +
+		"3296,1000,CLIENTE UNO,66137,27/06/2023,63025.21,11974.79,75000.00,,SUPIA,F.O. COMERCIAL BASICO.\n" +
+		"3297,1001,CLIENTE DOS,66138,27/06/2023,63025.21,11974.79,75000.00,3f7a9c,SUPIA,F.O. COMERCIAL BASICO.\n" +
+		"3298,1002,CLIENTE TRES,66139,27/06/2023,63025.21,11974.79,75000.00,,SUPIA,F.O. COMERCIAL BASICO.\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dr := &fakeDatabaseRepository{}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	billingData, err := mekano.Billing(path, "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturacion: %v", err)
+	}
+	if len(billingData) != 9 {
+		t.Fatalf("Se esperaban 9 líneas, se obtuvieron %d", len(billingData))
+	}
+	if _, ok := dr.invoices[""]; ok || len(dr.invoices) != 1 {
+		t.Errorf("Solo debe registrarse la factura con CUFE: %+v", dr.invoices)
+	}
+
+	billingData, err = mekano.Billing(path, "../test_files/extras.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar los archivos de facturacion: %v", err)
+	}
+	if len(billingData) != 6 {
+		t.Errorf("Las facturas sin CUFE no deben omitirse: se obtuvieron %d líneas", len(billingData))
+	}
+}
+
+// concurrentRepository simula otra ejecución que reserva consecutivos entre
+// la validación y la reserva.
+type concurrentRepository struct {
+	*fakeDatabaseRepository
+}
+
+func (c concurrentRepository) ReserveConsecutives(ctx context.Context, sequence string, n int) (*Reservation, error) {
+	c.consecutive += 10
+	return c.fakeDatabaseRepository.ReserveConsecutives(ctx, sequence, n)
+}
+
+func TestMekanoPaymentUsesReservedRange(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(concurrentRepository{dr}, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	paymentData, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+
+	for _, d := range paymentData {
+		if d.Numero != "15011" {
+			t.Errorf("Se esperaba el RC reservado 15011, se obtuvo %s", d.Numero)
+		}
+	}
+	if dr.receipts["107376"].Consecutive != 15011 || dr.payments[0].Consecutive != 15011 {
+		t.Errorf("El recibo y el historial deben usar el RC reservado: %+v %+v", dr.receipts, dr.payments)
+	}
+}
+
+func TestMekanoPaymentReleasesRangeWhenExportFails(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	exportPath := filepath.Join(t.TempDir(), "no-existe", "CONTABLE.txt")
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: exportPath})
+
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err == nil {
+		t.Fatal("Se esperaba un error al escribir la interfaz")
+	}
+	if dr.consecutive != 15000 || len(dr.payments) != 0 || len(dr.receipts) != 0 {
+		t.Errorf("La secuencia debe quedar como estaba y no registrarse nada: %d %+v %+v", dr.consecutive, dr.payments, dr.receipts)
+	}
+}
+
+// failingReceiptsRepository simula un error al registrar los recibos, por
+// ejemplo porque otra ejecución ya los registró.
+type failingReceiptsRepository struct {
+	*fakeDatabaseRepository
+}
+
+func (f failingReceiptsRepository) SaveReceipts(ctx context.Context, receipts []Receipt) error {
+	return errors.New("Duplicate entry '107376' for key 'PRIMARY'")
+}
+
+func TestMekanoPaymentFailsWhenReceiptsAreNotSaved(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	exportPath := testExportPath(t)
+	mekano := NewMekanoRepository(failingReceiptsRepository{dr}, config.DefaultMappings(), Options{ExportPath: exportPath})
+
+	_, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err == nil || !strings.Contains(err.Error(), "no se registraron los recibos") {
+		t.Fatalf("Se esperaba un error al registrar los recibos, se obtuvo: %v", err)
+	}
+
+	if _, err := os.Stat(exportPath); err != nil {
+		t.Errorf("La interfaz debió exportarse: %v", err)
+	}
+	if len(dr.payments) != 1 {
+		t.Errorf("La ejecución debe quedar en el historial aunque fallen los recibos: %+v", dr.payments)
+	}
+}
+
+// failingInvoicesRepository simula un error al registrar las facturas.
+type failingInvoicesRepository struct {
+	*fakeDatabaseRepository
+}
+
+func (f failingInvoicesRepository) SaveInvoices(ctx context.Context, invoices []Invoice) error {
+	return context.DeadlineExceeded
+}
+
+func TestMekanoBillingFailsWhenInvoicesAreNotSaved(t *testing.T) {
+	dr := &fakeDatabaseRepository{}
+	mekano := NewMekanoRepository(failingInvoicesRepository{dr}, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	_, err := mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Se esperaba el error al registrar las facturas, se obtuvo: %v", err)
+	}
+	if len(dr.billings) != 1 {
+		t.Errorf("La ejecución debe quedar en el historial aunque fallen las facturas: %+v", dr.billings)
+	}
+}
+
+// failingHistoryRepository simula un error al registrar la ejecución en el
+// historial.
+type failingHistoryRepository struct {
+	*fakeDatabaseRepository
+}
+
+func (f failingHistoryRepository) SavePayment(ctx context.Context, payment Payment) error {
+	return context.DeadlineExceeded
+}
+
+func (f failingHistoryRepository) SaveBilling(ctx context.Context, billing Billing) error {
+	return context.DeadlineExceeded
+}
+
+func TestMekanoFailsWhenHistoryIsNotSaved(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(failingHistoryRepository{dr}, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	_, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "no se registró la ejecución") {
+		t.Fatalf("Se esperaba el error al registrar el pago, se obtuvo: %v", err)
+	}
+	if len(dr.receipts) != 1 || len(dr.batches) != 1 {
+		t.Errorf("Los recibos y el lote deben registrarse aunque falle el historial: %+v %+v", dr.receipts, dr.batches)
+	}
+
+	_, err = mekano.Billing("../test_files/billing_test.xlsx", "../test_files/extras.xlsx")
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "no se registró la ejecución") {
+		t.Fatalf("Se esperaba el error al registrar la facturación, se obtuvo: %v", err)
+	}
+}
+
+// failingBatchRepository simula un error al guardar el lote.
+type failingBatchRepository struct {
+	*fakeDatabaseRepository
+}
+
+func (f failingBatchRepository) SaveBatch(ctx context.Context, batch Batch) (int64, error) {
+	return 0, context.DeadlineExceeded
+}
+
+func TestMekanoPaymentFailsWhenBatchIsNotSaved(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(failingBatchRepository{dr}, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	_, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Se esperaba el error al guardar el lote, se obtuvo: %v", err)
+	}
+
+	// Los recibos se registran igual, para no exportarlos otra vez.
+	if _, ok := dr.receipts["107376"]; !ok || len(dr.payments) != 1 || dr.payments[0].BatchID != 0 {
+		t.Errorf("Registro inesperado: %+v %+v", dr.receipts, dr.payments)
+	}
+}
+
+func TestMekanoPaymentSavesBatch(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	output := OutputOptions{Encoding: "windows-1252", CRLF: true}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{Operator: "caja1", ExportPath: testExportPath(t), Output: output})
+
+	paymentData, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+
+	if len(dr.batches) != 1 {
+		t.Fatalf("Se esperaba 1 lote, se obtuvieron %d", len(dr.batches))
+	}
+	batch := dr.batches[0]
+	if batch.Kind != BatchPayment || batch.Operator != "caja1" || batch.FileName != "../test_files/payment_test.xlsx" {
+		t.Errorf("Lote inesperado: %+v", batch)
+	}
+	if batch.Output == nil || *batch.Output != output {
+		t.Errorf("El lote debe guardar el formato de la interfaz: %+v", batch.Output)
+	}
+	if !reflect.DeepEqual(batch.Lines, paymentData) {
+		t.Errorf("Las líneas del lote no coinciden con la interfaz exportada")
+	}
+
+	sum, err := checksum("../test_files/payment_test.xlsx")
+	if err != nil || batch.Checksum != sum || len(sum) != 64 {
+		t.Errorf("Checksum inesperado: %q, %q, %v", batch.Checksum, sum, err)
+	}
+}
+
+func TestMekanoPaymentSavesStatistics(t *testing.T) {
+	dr := &fakeDatabaseRepository{consecutive: 15000}
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+
+	if _, err := mekano.Payment("../test_files/payment_test.xlsx"); err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+
+	if len(dr.payments) != 1 {
+		t.Fatalf("Se esperaba 1 pago registrado, se obtuvieron %d", len(dr.payments))
+	}
+	p := dr.payments[0]
+	expected := Payment{
+		Consecutive: 15001,
+		CreateAt:    p.CreateAt,
+		FileName:    "../test_files/payment_test.xlsx",
+		FirstRC:     15001,
+		BatchID:     1,
+		Rows:        1,
+		Susuerte:    75000_00,
+		Total:       75000_00,
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Errorf("Pago registrado inesperado.\nEsperado: %+v\nObtenido: %+v", expected, p)
+	}
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Las migraciones son archivos NNNN_nombre.up.sql y NNNN_nombre.down.sql
+// que se compilan dentro del binario, en un directorio por motor.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration es una versión del esquema con las sentencias para aplicarla y
+// revertirla.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus indica si una migración ya fue aplicada y cuándo.
+type MigrationStatus struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+}
+
+// loadMigrations lee las migraciones del directorio ordenadas por versión.
+func loadMigrations(files fs.FS, dir string) ([]Migration, error) {
+	names, err := fs.Glob(files, dir+"/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, name := range names {
+		base := path.Base(name)
+
+		var direction string
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+		default:
+			return nil, fmt.Errorf("migración %s: falta el sufijo .up.sql o .down.sql", base)
+		}
+
+		version, title, ok := strings.Cut(strings.TrimSuffix(base, "."+direction+".sql"), "_")
+		v, err := strconv.Atoi(version)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migración %s: el nombre debe ser NNNN_nombre", base)
+		}
+
+		content, err := fs.ReadFile(files, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[v]
+		if !ok {
+			m = &Migration{Version: v, Name: title}
+			byVersion[v] = m
+		}
+		if m.Name != title {
+			return nil, fmt.Errorf("migración %d: nombres distintos %q y %q", v, m.Name, title)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migración %04d_%s: falta el archivo up o down", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migración %04d_%s: se esperaba la versión %d", m.Version, m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// splitStatements separa un archivo de migración en sentencias, porque el
+// driver de MySQL no ejecuta varias en una sola llamada. Las migraciones no
+// deben usar ";" dentro de cadenas ni comentarios.
+func splitStatements(content string) []string {
+	var statements []string
+	for _, s := range strings.Split(content, ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			statements = append(statements, s)
+		}
+	}
+	return statements
+}
+
+// appliedMigrations devuelve la fecha de aplicación de cada versión del
+// esquema, creando la tabla de versiones si no existe.
+func (r *DatabaseRepository) appliedMigrations(ctx context.Context) (map[int]string, error) {
+	if _, err := r.db.ExecContext(ctx, r.dialect.schemaTable); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, "SELECT version, applied_at FROM mekanoschema")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]string{}
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// MigrationStatus devuelve todas las migraciones embebidas indicando cuáles
+// ya fueron aplicadas.
+func (r *DatabaseRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(migrationFiles, r.dialect.migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, m := range migrations {
+		appliedAt, ok := applied[m.Version]
+		status[i] = MigrationStatus{Migration: m, Applied: ok, AppliedAt: appliedAt}
+	}
+	return status, nil
+}
+
+// MigrateUp aplica en orden las migraciones pendientes, como máximo steps si
+// es mayor que cero, y devuelve las aplicadas.
+func (r *DatabaseRepository) MigrateUp(ctx context.Context, steps int) ([]Migration, error) {
+	status, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, s := range status {
+		if s.Applied {
+			continue
+		}
+		if steps > 0 && len(done) == steps {
+			break
+		}
+
+		err := r.runMigration(ctx, s.Up, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO mekanoschema (version, name, applied_at) VALUES (?, ?, ?)",
+				s.Version, s.Name, time.Now().Format("2006-01-02 15:04:05"))
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migración %04d_%s: %w", s.Version, s.Name, err)
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// MigrateDown revierte las últimas steps migraciones aplicadas, en orden
+// inverso, y devuelve las revertidas.
+func (r *DatabaseRepository) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	status, err := r.MigrationStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(status) - 1; i >= 0 && len(done) < steps; i-- {
+		s := status[i]
+		if !s.Applied {
+			continue
+		}
+
+		err := r.runMigration(ctx, s.Down, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, "DELETE FROM mekanoschema WHERE version = ?", s.Version)
+			return err
+		})
+		if err != nil {
+			return done, fmt.Errorf("migración %04d_%s: %w", s.Version, s.Name, err)
+		}
+		done = append(done, s.Migration)
+	}
+	return done, nil
+}
+
+// runMigration ejecuta las sentencias de una migración y registra el cambio
+// de versión en la misma transacción. En SQLite una falla revierte todo, pero
+// MySQL confirma implícitamente cada sentencia DDL: por eso sus migraciones
+// crean tablas con IF NOT EXISTS y modifican cada tabla con un solo ALTER
+// TABLE, que se aplica completo o no se aplica. Si falla una migración que
+// modifica varias tablas, las ya modificadas deben revertirse a mano antes de
+// repetirla.
+func (r *DatabaseRepository) runMigration(ctx context.Context, content string, record func(tx *sql.Tx) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, statement := range splitStatements(content) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	if err := record(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
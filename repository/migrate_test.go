@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	var versions []string
+	for _, d := range []dialect{mysqlDialect, sqliteDialect} {
+		migrations, err := loadMigrations(migrationFiles, d.migrations)
+		if err != nil {
+			t.Fatalf("Error loading %s migrations: %v", d.driver, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("Expected embedded %s migrations", d.driver)
+		}
+
+		// Cada tabla que usa DatabaseRepository debe crearse en alguna migración.
+		var up strings.Builder
+		var names []string
+		for _, m := range migrations {
+			up.WriteString(m.Up)
+			names = append(names, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+		for _, table := range []string{"mekanopayments", "mekanobilling", "mekanomappings", "mekanoreceipts", "mekanoinvoices", "mekanosequences"} {
+			if !strings.Contains(up.String(), "CREATE TABLE IF NOT EXISTS "+table+" ") {
+				t.Errorf("No %s migration creates table %s", d.driver, table)
+			}
+		}
+
+		// Los motores deben tener las mismas versiones del esquema.
+		if versions == nil {
+			versions = names
+		} else if !reflect.DeepEqual(versions, names) {
+			t.Errorf("Expected %s migrations %v, got %v", d.driver, versions, names)
+		}
+	}
+}
+
+func TestLoadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+	}{
+		{
+			name: "missing down",
+			files: fstest.MapFS{
+				"migrations/0001_a.up.sql": {Data: []byte("SELECT 1")},
+			},
+		},
+		{
+			name: "gap in versions",
+			files: fstest.MapFS{
+				"migrations/0001_a.up.sql":   {Data: []byte("SELECT 1")},
+				"migrations/0001_a.down.sql": {Data: []byte("SELECT 1")},
+				"migrations/0003_c.up.sql":   {Data: []byte("SELECT 1")},
+				"migrations/0003_c.down.sql": {Data: []byte("SELECT 1")},
+			},
+		},
+		{
+			name: "bad name",
+			files: fstest.MapFS{
+				"migrations/initial.up.sql":   {Data: []byte("SELECT 1")},
+				"migrations/initial.down.sql": {Data: []byte("SELECT 1")},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if _, err := loadMigrations(tt.files, "migrations"); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("CREATE TABLE a (id INT);\n\nDROP TABLE b;\n")
+	want := []string{"CREATE TABLE a (id INT)", "DROP TABLE b"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mekanobilling;
+DROP TABLE IF EXISTS mekanopayments;
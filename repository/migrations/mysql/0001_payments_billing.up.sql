@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS mekanopayments (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	consecutive INT NOT NULL,
+	create_at DATE NOT NULL,
+	file_name VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mekanobilling (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	debit BIGINT NOT NULL,
+	credit BIGINT NOT NULL,
+	base BIGINT NOT NULL,
+	create_at DATE NOT NULL,
+	file_name VARCHAR(255) NOT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS mekanomappings (
+	section VARCHAR(32) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	value VARCHAR(32) NOT NULL,
+	PRIMARY KEY (section, name)
+);
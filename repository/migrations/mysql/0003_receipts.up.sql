@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS mekanoreceipts (
+	receipt VARCHAR(64) PRIMARY KEY,
+	subscriber VARCHAR(64) NOT NULL,
+	amount VARCHAR(32) NOT NULL,
+	payment_date VARCHAR(32) NOT NULL,
+	consecutive INT NOT NULL,
+	create_at DATE NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS mekanoinvoices (
+	cufe VARCHAR(128) PRIMARY KEY,
+	number VARCHAR(64) NOT NULL,
+	subscriber VARCHAR(64) NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	create_at DATE NOT NULL
+);
@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS mekanosequences (
+	name VARCHAR(32) PRIMARY KEY,
+	value INT NOT NULL
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS mekanobatchlines;
+DROP TABLE IF EXISTS mekanobatches;
@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS mekanobatches (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	kind VARCHAR(16) NOT NULL,
+	file_name VARCHAR(255) NOT NULL,
+	checksum CHAR(64) NOT NULL,
+	operator VARCHAR(64) NOT NULL,
+	create_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mekanobatchlines (
+	batch_id INT NOT NULL,
+	line INT NOT NULL,
+	tipo VARCHAR(64) NOT NULL,
+	prefijo VARCHAR(64) NOT NULL,
+	numero VARCHAR(64) NOT NULL,
+	secuencia VARCHAR(64) NOT NULL,
+	fecha VARCHAR(64) NOT NULL,
+	cuenta VARCHAR(64) NOT NULL,
+	terceros VARCHAR(64) NOT NULL,
+	centro_costos VARCHAR(64) NOT NULL,
+	nota VARCHAR(512) NOT NULL,
+	debito VARCHAR(64) NOT NULL,
+	credito VARCHAR(64) NOT NULL,
+	base VARCHAR(64) NOT NULL,
+	aplica VARCHAR(64) NOT NULL,
+	tipo_anexo VARCHAR(64) NOT NULL,
+	prefijo_anexo VARCHAR(64) NOT NULL,
+	numero_anexo VARCHAR(64) NOT NULL,
+	usuario VARCHAR(64) NOT NULL,
+	signo VARCHAR(64) NOT NULL,
+	cuenta_cobrar VARCHAR(64) NOT NULL,
+	cuenta_pagar VARCHAR(64) NOT NULL,
+	nombre_tercero VARCHAR(512) NOT NULL,
+	nombre_centro VARCHAR(512) NOT NULL,
+	interfaz VARCHAR(64) NOT NULL,
+	PRIMARY KEY (batch_id, line),
+	FOREIGN KEY (batch_id) REFERENCES mekanobatches (id) ON DELETE CASCADE
+);
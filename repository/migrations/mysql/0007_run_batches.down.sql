@@ -0,0 +1,2 @@
+ALTER TABLE mekanobilling DROP COLUMN batch_id;
+ALTER TABLE mekanopayments DROP COLUMN batch_id;
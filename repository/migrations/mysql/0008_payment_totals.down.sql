@@ -0,0 +1,9 @@
+ALTER TABLE mekanopayments
+	DROP COLUMN total,
+	DROP COLUMN cash,
+	DROP COLUMN payu,
+	DROP COLUMN susuerte,
+	DROP COLUMN davivienda,
+	DROP COLUMN bancolombia,
+	DROP COLUMN rows_count,
+	DROP COLUMN rc_start;
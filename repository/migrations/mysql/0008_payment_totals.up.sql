@@ -0,0 +1,9 @@
+ALTER TABLE mekanopayments
+	ADD COLUMN rc_start INT NOT NULL DEFAULT 0,
+	ADD COLUMN rows_count INT NOT NULL DEFAULT 0,
+	ADD COLUMN bancolombia BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN davivienda BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN susuerte BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN payu BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN cash BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN total BIGINT NOT NULL DEFAULT 0;
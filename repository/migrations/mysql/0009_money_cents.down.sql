@@ -0,0 +1,2 @@
+UPDATE mekanobilling SET debit = debit DIV 100, credit = credit DIV 100, base = base DIV 100;
+UPDATE mekanopayments SET bancolombia = bancolombia DIV 100, davivienda = davivienda DIV 100, susuerte = susuerte DIV 100, payu = payu DIV 100, cash = cash DIV 100, total = total DIV 100;
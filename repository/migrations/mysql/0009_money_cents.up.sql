@@ -0,0 +1,2 @@
+UPDATE mekanopayments SET bancolombia = bancolombia * 100, davivienda = davivienda * 100, susuerte = susuerte * 100, payu = payu * 100, cash = cash * 100, total = total * 100;
+UPDATE mekanobilling SET debit = debit * 100, credit = credit * 100, base = base * 100;
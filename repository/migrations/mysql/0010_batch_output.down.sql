@@ -0,0 +1,3 @@
+ALTER TABLE mekanobatches
+	DROP COLUMN crlf,
+	DROP COLUMN encoding;
@@ -0,0 +1,3 @@
+ALTER TABLE mekanobatches
+	ADD COLUMN encoding VARCHAR(32) NULL,
+	ADD COLUMN crlf BOOLEAN NULL;
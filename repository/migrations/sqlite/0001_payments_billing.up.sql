@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS mekanopayments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	consecutive INTEGER NOT NULL,
+	create_at TEXT NOT NULL,
+	file_name TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mekanobilling (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	debit INTEGER NOT NULL,
+	credit INTEGER NOT NULL,
+	base INTEGER NOT NULL,
+	create_at TEXT NOT NULL,
+	file_name TEXT NOT NULL
+);
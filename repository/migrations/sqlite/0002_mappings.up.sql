@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS mekanomappings (
+	section TEXT NOT NULL,
+	name TEXT NOT NULL,
+	value TEXT NOT NULL,
+	PRIMARY KEY (section, name)
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS mekanoreceipts (
+	receipt TEXT PRIMARY KEY,
+	subscriber TEXT NOT NULL,
+	amount TEXT NOT NULL,
+	payment_date TEXT NOT NULL,
+	consecutive INTEGER NOT NULL,
+	create_at TEXT NOT NULL
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS mekanoinvoices (
+	cufe TEXT PRIMARY KEY,
+	number TEXT NOT NULL,
+	subscriber TEXT NOT NULL,
+	file_name TEXT NOT NULL,
+	create_at TEXT NOT NULL
+);
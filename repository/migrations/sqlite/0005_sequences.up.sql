@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS mekanosequences (
+	name TEXT PRIMARY KEY,
+	value INTEGER NOT NULL
+);
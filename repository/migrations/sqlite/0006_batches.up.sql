@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS mekanobatches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL,
+	file_name TEXT NOT NULL,
+	checksum TEXT NOT NULL,
+	operator TEXT NOT NULL,
+	create_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS mekanobatchlines (
+	batch_id INTEGER NOT NULL,
+	line INTEGER NOT NULL,
+	tipo TEXT NOT NULL,
+	prefijo TEXT NOT NULL,
+	numero TEXT NOT NULL,
+	secuencia TEXT NOT NULL,
+	fecha TEXT NOT NULL,
+	cuenta TEXT NOT NULL,
+	terceros TEXT NOT NULL,
+	centro_costos TEXT NOT NULL,
+	nota TEXT NOT NULL,
+	debito TEXT NOT NULL,
+	credito TEXT NOT NULL,
+	base TEXT NOT NULL,
+	aplica TEXT NOT NULL,
+	tipo_anexo TEXT NOT NULL,
+	prefijo_anexo TEXT NOT NULL,
+	numero_anexo TEXT NOT NULL,
+	usuario TEXT NOT NULL,
+	signo TEXT NOT NULL,
+	cuenta_cobrar TEXT NOT NULL,
+	cuenta_pagar TEXT NOT NULL,
+	nombre_tercero TEXT NOT NULL,
+	nombre_centro TEXT NOT NULL,
+	interfaz TEXT NOT NULL,
+	PRIMARY KEY (batch_id, line),
+	FOREIGN KEY (batch_id) REFERENCES mekanobatches (id) ON DELETE CASCADE
+);
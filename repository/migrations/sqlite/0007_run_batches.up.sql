@@ -0,0 +1,2 @@
+ALTER TABLE mekanopayments ADD COLUMN batch_id INTEGER NULL;
+ALTER TABLE mekanobilling ADD COLUMN batch_id INTEGER NULL;
@@ -0,0 +1,8 @@
+ALTER TABLE mekanopayments DROP COLUMN total;
+ALTER TABLE mekanopayments DROP COLUMN cash;
+ALTER TABLE mekanopayments DROP COLUMN payu;
+ALTER TABLE mekanopayments DROP COLUMN susuerte;
+ALTER TABLE mekanopayments DROP COLUMN davivienda;
+ALTER TABLE mekanopayments DROP COLUMN bancolombia;
+ALTER TABLE mekanopayments DROP COLUMN rows_count;
+ALTER TABLE mekanopayments DROP COLUMN rc_start;
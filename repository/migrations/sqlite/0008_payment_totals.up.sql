@@ -0,0 +1,8 @@
+ALTER TABLE mekanopayments ADD COLUMN rc_start INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN rows_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN bancolombia INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN davivienda INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN susuerte INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN payu INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN cash INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE mekanopayments ADD COLUMN total INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE mekanobatches DROP COLUMN crlf;
+ALTER TABLE mekanobatches DROP COLUMN encoding;
@@ -0,0 +1,2 @@
+ALTER TABLE mekanobatches ADD COLUMN encoding TEXT NULL;
+ALTER TABLE mekanobatches ADD COLUMN crlf INTEGER NULL;
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money es un valor en pesos guardado en centavos, para sumar y comparar sin
+// los errores de redondeo de float64.
+type Money int64
+
+// RoundingPolicy indica cómo se redondean los valores de los archivos de
+// entrada al leerlos.
+type RoundingPolicy string
+
+const (
+	// RoundPeso redondea al peso, con las mitades hacia arriba. Es la regla
+	// que se usó siempre para la facturación.
+	RoundPeso RoundingPolicy = "peso"
+	// RoundHalfUp redondea al centavo, con las mitades hacia arriba.
+	RoundHalfUp RoundingPolicy = "half-up"
+	// RoundHalfEven redondea al centavo, con las mitades al par más cercano.
+	RoundHalfEven RoundingPolicy = "half-even"
+)
+
+// RoundingEnv es la variable de entorno con la política de redondeo cuando no
+// se indica el flag -rounding.
+const RoundingEnv = "MEKANO_ROUNDING"
+
+// ParseRoundingPolicy valida el nombre de una política de redondeo. Si está
+// vacío se usa RoundPeso.
+func ParseRoundingPolicy(s string) (RoundingPolicy, error) {
+	switch p := RoundingPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return RoundPeso, nil
+	case RoundPeso, RoundHalfUp, RoundHalfEven:
+		return p, nil
+	default:
+		return "", fmt.Errorf("política de redondeo no soportada: %q (use %s, %s o %s)", s, RoundPeso, RoundHalfUp, RoundHalfEven)
+	}
+}
+
+// maxMoneyDigits limita la parte entera para que el valor en centavos quepa
+// en un int64.
+const maxMoneyDigits = 15
+
+// ParseMoney lee un valor con punto decimal, como los que entregan los
+// archivos de entrada, y lo redondea según policy. Los valores en notación
+// científica, que a veces exporta Excel, también se aceptan.
+func ParseMoney(s string, policy RoundingPolicy) (Money, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("valor inválido: %q", s)
+
+	if strings.ContainsAny(s, "eE") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, invalid
+		}
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !isDigits(whole) || !isDigits(frac) {
+		return 0, invalid
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > maxMoneyDigits {
+		return 0, fmt.Errorf("valor fuera de rango: %q", s)
+	}
+
+	decimals := 2
+	if policy == RoundPeso {
+		decimals = 0
+	}
+	kept, dropped := frac, ""
+	if len(frac) > decimals {
+		kept, dropped = frac[:decimals], frac[decimals:]
+	}
+	kept += strings.Repeat("0", decimals-len(kept))
+
+	var value int64
+	for _, c := range whole + kept {
+		value = value*10 + int64(c-'0')
+	}
+
+	if dropped != "" {
+		tail := strings.TrimRight(dropped[1:], "0") != ""
+		switch {
+		case dropped[0] > '5', dropped[0] == '5' && tail:
+			value++
+		case dropped[0] == '5' && (policy != RoundHalfEven || value%2 == 1):
+			value++
+		}
+	}
+
+	for ; decimals < 2; decimals++ {
+		value *= 10
+	}
+	if negative {
+		value = -value
+	}
+	return Money(value), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// lineAmount lee un valor ya escrito en una línea de la interfaz. Las líneas
+// de lotes anteriores pueden tener otros formatos, como "75000.000000"; un
+// valor vacío o inválido cuenta como cero.
+func lineAmount(s string) Money {
+	m, _ := ParseMoney(s, RoundHalfEven)
+	return m
+}
+
+// String devuelve el valor con dos decimales y punto decimal, el único
+// formato que se escribe en la interfaz.
+func (m Money) String() string {
+	sign, v := "", int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON escribe el valor como número en pesos.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON lee un número en pesos; también acepta los enteros que
+// guardaban los diarios anteriores a este tipo.
+func (m *Money) UnmarshalJSON(b []byte) error {
+	v, err := ParseMoney(strings.Trim(string(b), `"`), RoundHalfEven)
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// InvalidAmount es un valor de un archivo de entrada que no es un monto.
+type InvalidAmount struct {
+	Row    int
+	Column string
+	Value  string
+}
+
+// InvalidAmountError se devuelve cuando el archivo tiene montos inválidos,
+// para no exportar una interfaz con valores en cero.
+type InvalidAmountError struct {
+	Amounts []InvalidAmount
+}
+
+func (e *InvalidAmountError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d montos inválidos, no se exportó la interfaz:", len(e.Amounts))
+	for _, a := range e.Amounts {
+		fmt.Fprintf(&sb, "\n  fila %d, %s: %q", a.Row, a.Column, a.Value)
+	}
+	return sb.String()
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value    string
+		policy   RoundingPolicy
+		expected Money
+	}{
+		{"75000", RoundPeso, 75000_00},
+		{"63949.5", RoundPeso, 63950_00},
+		{"63949.49", RoundPeso, 63949_00},
+		{"-10.5", RoundPeso, -11_00},
+		{"16525.125", RoundHalfUp, 16525_13},
+		{"16525.125", RoundHalfEven, 16525_12},
+		{"16525.135", RoundHalfEven, 16525_14},
+		{"16525.1250001", RoundHalfEven, 16525_13},
+		{"0.1", RoundHalfEven, 10},
+		{".5", RoundHalfUp, 50},
+		{"7.5E4", RoundPeso, 75000_00},
+		{" 80475.000000 ", RoundHalfEven, 80475_00},
+	}
+
+	for _, tt := range tests {
+		m, err := ParseMoney(tt.value, tt.policy)
+		if err != nil {
+			t.Errorf("%q %s: %v", tt.value, tt.policy, err)
+			continue
+		}
+		if m != tt.expected {
+			t.Errorf("%q %s: se esperaba %s, se obtuvo %s", tt.value, tt.policy, tt.expected, m)
+		}
+	}
+
+	for _, value := range []string{"", ".", "-", "75.000,50", "1,5", "abc", "1234567890123456"} {
+		if _, err := ParseMoney(value, RoundPeso); err == nil {
+			t.Errorf("%q: se esperaba un error", value)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	for m, expected := range map[Money]string{0: "0.00", 75000_00: "75000.00", 5: "0.05", -1_50: "-1.50"} {
+		if m.String() != expected {
+			t.Errorf("%d: se esperaba %q, se obtuvo %q", int64(m), expected, m.String())
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var p Payment
+	// Los diarios anteriores guardaban los totales como pesos enteros.
+	if err := json.Unmarshal([]byte(`{"Susuerte": 75000, "Total": 75000.5}`), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Susuerte != 75000_00 || p.Total != 75000_50 {
+		t.Errorf("Totales inesperados: %+v", p)
+	}
+
+	b, err := json.Marshal(billingStatistics{Debito: 103500_00, Credito: 103500_00, Base: 86975_25})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"debito":103500.00,"credito":103500.00,"base":86975.25}`; string(b) != expected {
+		t.Errorf("Se esperaba %s, se obtuvo %s", expected, b)
+	}
+}
+
+func TestParseRoundingPolicy(t *testing.T) {
+	if p, err := ParseRoundingPolicy(""); err != nil || p != RoundPeso {
+		t.Errorf("Se esperaba %s por defecto, se obtuvo %q (%v)", RoundPeso, p, err)
+	}
+	if p, err := ParseRoundingPolicy("Half-Even"); err != nil || p != RoundHalfEven {
+		t.Errorf("Se esperaba %s, se obtuvo %q (%v)", RoundHalfEven, p, err)
+	}
+	if _, err := ParseRoundingPolicy("bancario"); err == nil {
+		t.Error("Se esperaba un error por una política desconocida")
+	}
+}
+
+func TestBuildPaymentInvalidAmount(t *testing.T) {
+	mr := &mekanoRepository{m: config.DefaultMappings(), opts: Options{Rounding: RoundPeso}}
+	columns := map[string]int{colPagoTotal: 0, colPagoCobrador: 1, colPagoRecibo: 2}
+	payments := []record{
+		{values: []string{"75000.4", "SUSUERTE S", "1"}, columns: columns, line: 2},
+		{values: []string{"75.000,00", "SUSUERTE S", "2"}, columns: columns, line: 3},
+		{values: []string{"", "SUSUERTE S", "3"}, columns: columns, line: 4},
+	}
+
+	p := mr.buildPayment(payments, 15000)
+	if p.data[0].Credito != "75000.00" || p.data[4].Credito != "0.00" {
+		t.Errorf("Montos inesperados: %q, %q", p.data[0].Credito, p.data[4].Credito)
+	}
+
+	var invalid *InvalidAmountError
+	if err := p.checkAmounts(); !errors.As(err, &invalid) || len(invalid.Amounts) != 1 || invalid.Amounts[0].Row != 3 {
+		t.Errorf("Se esperaba un InvalidAmountError en la fila 3, se obtuvo: %v", err)
+	}
+}
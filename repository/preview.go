@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// preview muestra las líneas generadas y su resumen sin tocar la carpeta de
+// exportación ni la base de datos. Si se configuró PreviewPath, la interfaz
+// también se escribe en esa ruta.
+func (mr *mekanoRepository) preview(data []MekanoDataStruct, summary interface{}) error {
+	printPreview(os.Stdout, data)
+
+	result, err := json.MarshalIndent(summary, "", " ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(result))
+
+	if mr.opts.PreviewPath != "" {
+		if err := writeInterfaceFile(mr.opts.PreviewPath, data, mr.opts.Output); err != nil {
+			return err
+		}
+		fmt.Printf("Vista previa escrita en %s\n", mr.opts.PreviewPath)
+	}
+
+	fmt.Println("Modo de prueba: no se escribió CONTABLE.txt ni se guardó el consecutivo")
+	return nil
+}
+
+func printPreview(out io.Writer, data []MekanoDataStruct) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIPO\tNUMERO\tFECHA\tCUENTA\tTERCERO\tC.COSTOS\tDEBITO\tCREDITO\tBASE\tNOMBRE")
+	for _, d := range data {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			d.Tipo, d.Numero, d.Fecha, d.Cuenta, d.Terceros, d.CentroCostos, d.Debito, d.Credito, d.Base, d.NombreTercero)
+	}
+	w.Flush()
+}
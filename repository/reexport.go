@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RunQuery identifica una ejecución registrada: por un RC de su rango o por
+// parte del nombre del archivo de entrada. Se usa el primer criterio no vacío
+// en ese orden.
+type RunQuery struct {
+	RC   int
+	File string
+}
+
+// UnbatchedRunError indica que la ejecución es anterior a los lotes, por lo
+// que su interfaz solo puede reconstruirse desde el archivo de pagos original.
+type UnbatchedRunError struct {
+	Payment Payment
+}
+
+func (e *UnbatchedRunError) Error() string {
+	return fmt.Sprintf("la ejecución de pagos %s (RC %d-%d) no tiene lote guardado: indique el archivo de pagos original para reconstruirla",
+		e.Payment.FileName, e.Payment.FirstRC, e.Payment.Consecutive)
+}
+
+// FindRun busca el lote de una ejecución registrada sin modificar nada en la
+// base de datos.
+func FindRun(ctx context.Context, dr DatabaseRepositoryInterface, q RunQuery) (Batch, error) {
+	switch {
+	case q.RC != 0:
+		payments, err := dr.ListPayments(ctx, HistoryFilter{})
+		if err != nil {
+			return Batch{}, err
+		}
+		for _, p := range payments {
+			if p.FirstRC <= q.RC && q.RC <= p.Consecutive {
+				if p.BatchID == 0 {
+					return Batch{}, &UnbatchedRunError{Payment: p}
+				}
+				return dr.GetBatch(ctx, p.BatchID)
+			}
+		}
+		return Batch{}, fmt.Errorf("ninguna ejecución registrada asignó el RC %d", q.RC)
+
+	case q.File != "":
+		filter := HistoryFilter{File: q.File}
+		payments, err := dr.ListPayments(ctx, filter)
+		if err != nil {
+			return Batch{}, err
+		}
+		billings, err := dr.ListBillings(ctx, filter)
+		if err != nil {
+			return Batch{}, err
+		}
+
+		var runs []string
+		var batchID int64
+		var unbatched *UnbatchedRunError
+		for _, p := range payments {
+			runs = append(runs, fmt.Sprintf("pagos %s RC %d-%d lote %d", p.FileName, p.FirstRC, p.Consecutive, p.BatchID))
+			batchID = p.BatchID
+			if p.BatchID == 0 {
+				unbatched = &UnbatchedRunError{Payment: p}
+			}
+		}
+		for _, b := range billings {
+			runs = append(runs, fmt.Sprintf("facturación %s del %s lote %d", b.FileName, b.CreateAt, b.BatchID))
+			batchID = b.BatchID
+		}
+
+		switch {
+		case len(runs) == 0:
+			return Batch{}, fmt.Errorf("ninguna ejecución registrada coincide con el archivo %q", q.File)
+		case len(runs) > 1:
+			return Batch{}, fmt.Errorf("varias ejecuciones coinciden con el archivo %q, indique un RC o regenere el lote con batches reexport:\n  %s", q.File, strings.Join(runs, "\n  "))
+		case unbatched != nil:
+			return Batch{}, unbatched
+		case batchID == 0:
+			return Batch{}, fmt.Errorf("la ejecución de facturación %s no tiene lote guardado", q.File)
+		}
+		return dr.GetBatch(ctx, batchID)
+	}
+	return Batch{}, fmt.Errorf("indique un RC o el archivo de la ejecución")
+}
+
+// RebuildPayment reconstruye desde el archivo de pagos original la interfaz
+// de una ejecución registrada sin lote, con los mismos RC. Si los recibos de
+// la ejecución quedaron registrados, solo se incluyen las filas que recibieron
+// un RC del rango, en el mismo orden en que se numeraron. No reserva
+// consecutivos ni guarda nada en la base de datos.
+//
+// Lo que no quedó registrado no se puede reproducir: las cuentas salen de los
+// mapeos actuales, los montos se redondean con la política de mr y se
+// escriben con dos decimales, y la hora de exportación es las 00:00 del día
+// de la ejecución.
+func (mr *mekanoRepository) RebuildPayment(file string, run Payment) ([]MekanoDataStruct, error) {
+	if run.FirstRC == 0 {
+		return nil, fmt.Errorf("no se conoce el primer RC de la ejecución %s", run.FileName)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	payments, err := mr.readPayments(file)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers := make([]string, len(payments))
+	for i, row := range payments {
+		numbers[i] = row.get(colPagoRecibo)
+	}
+	recorded, err := mr.dr.GetReceipts(ctx, numbers)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recorded) > 0 {
+		var rows []record
+		for _, row := range payments {
+			r, ok := recorded[row.get(colPagoRecibo)]
+			if ok && run.FirstRC <= r.Consecutive && r.Consecutive <= run.Consecutive {
+				rows = append(rows, row)
+			}
+		}
+		payments = rows
+	}
+
+	if expected := run.Consecutive - run.FirstRC + 1; len(payments) != expected {
+		return nil, fmt.Errorf("el archivo %s tiene %d pagos de la ejecución y se esperaban %d (RC %d-%d)",
+			file, len(payments), expected, run.FirstRC, run.Consecutive)
+	}
+
+	p := mr.buildPayment(payments, run.FirstRC-1)
+	if err := p.checkAmounts(); err != nil {
+		return nil, err
+	}
+	for _, receipt := range p.receipts {
+		if r, ok := recorded[receipt.Number]; ok && r.Consecutive != receipt.Consecutive {
+			return nil, fmt.Errorf("el recibo %s recibió el RC %d y no el %d: el archivo no corresponde a la ejecución", receipt.Number, r.Consecutive, receipt.Consecutive)
+		}
+	}
+	if err := mr.checkUnmapped(p.unmapped); err != nil {
+		return nil, err
+	}
+
+	// La hora exacta de la exportación original no quedó registrada.
+	if date, err := time.Parse("2006-01-02", run.CreateAt); err == nil {
+		for i := range p.data {
+			p.data[i].Interface = date.Format("02/01/2006 15:04")
+		}
+	}
+	return p.data, nil
+}
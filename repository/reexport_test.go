@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestFindRun(t *testing.T) {
+	ctx := context.Background()
+	dr := newSQLiteTestRepository(t)
+
+	// Una ejecución anterior a los lotes.
+	legacy := Payment{Consecutive: 15000, FirstRC: 14990, CreateAt: "2023-01-13", FileName: "pagos_enero.xlsx"}
+	if err := dr.SavePayment(ctx, legacy); err != nil {
+		t.Fatalf("Error al guardar el pago: %v", err)
+	}
+
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{ExportPath: testExportPath(t)})
+	data, err := mekano.Payment("../test_files/payment_test.xlsx")
+	if err != nil {
+		t.Fatalf("Error al procesar el archivo de pagos: %v", err)
+	}
+
+	before, err := dr.GetConsecutive(ctx, SequenceRC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs, err := dr.ListPayments(ctx, HistoryFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []RunQuery{{RC: 15001}, {File: "payment_test"}} {
+		batch, err := FindRun(ctx, dr, q)
+		if err != nil {
+			t.Fatalf("%+v: error al buscar la ejecución: %v", q, err)
+		}
+		if !reflect.DeepEqual(batch.Lines, data) {
+			t.Errorf("%+v: se esperaban las líneas exportadas, se obtuvo: %+v", q, batch.Lines)
+		}
+	}
+
+	var unbatched *UnbatchedRunError
+	if _, err := FindRun(ctx, dr, RunQuery{RC: 14995}); !errors.As(err, &unbatched) || unbatched.Payment.FileName != legacy.FileName {
+		t.Errorf("Se esperaba UnbatchedRunError, se obtuvo: %v", err)
+	}
+	if _, err := FindRun(ctx, dr, RunQuery{RC: 20000}); err == nil {
+		t.Error("Se esperaba un error para un RC no asignado")
+	}
+	if _, err := FindRun(ctx, dr, RunQuery{File: "xlsx"}); err == nil {
+		t.Error("Se esperaba un error cuando varias ejecuciones coinciden")
+	}
+
+	// Buscar no debe tocar la secuencia ni el historial.
+	after, _ := dr.GetConsecutive(ctx, SequenceRC)
+	again, _ := dr.ListPayments(ctx, HistoryFilter{})
+	if after != before || !reflect.DeepEqual(again, runs) {
+		t.Errorf("La búsqueda modificó la base de datos: RC %d -> %d", before, after)
+	}
+}
+
+func TestRebuildPayment(t *testing.T) {
+	ctx := context.Background()
+	dr := newSQLiteTestRepository(t)
+
+	run := Payment{Consecutive: 15001, FirstRC: 15001, CreateAt: "2023-01-13", FileName: "payment_test.xlsx", Rows: 1}
+	if err := dr.SavePayment(ctx, run); err != nil {
+		t.Fatalf("Error al guardar el pago: %v", err)
+	}
+
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{})
+	lines, err := mekano.RebuildPayment("../test_files/payment_test.xlsx", run)
+	if err != nil {
+		t.Fatalf("Error al reconstruir la ejecución: %v", err)
+	}
+	if len(lines) == 0 {
+		t.Fatal("Se esperaban líneas reconstruidas")
+	}
+	for _, l := range lines {
+		if l.Numero != "15001" || l.Interface != "13/01/2023 00:00" {
+			t.Errorf("Línea reconstruida inesperada: %+v", l)
+		}
+	}
+
+	// El archivo no corresponde a una ejecución de dos RC.
+	run.Consecutive = 15002
+	if _, err := mekano.RebuildPayment("../test_files/payment_test.xlsx", run); err == nil {
+		t.Error("Se esperaba un error por la cantidad de pagos")
+	}
+
+	consecutive, _ := dr.GetConsecutive(ctx, SequenceRC)
+	if consecutive != 15001 {
+		t.Errorf("La reconstrucción modificó la secuencia: %d", consecutive)
+	}
+}
+
+func TestRebuildPaymentInvalidAmount(t *testing.T) {
+	ctx := context.Background()
+	dr := newSQLiteTestRepository(t)
+
+	path := filepath.Join(t.TempDir(), "pagos.csv")
+	content := "N° Abonado,Documento,Cliente,Nro Recibo,Fecha,Total Pago,Cobrador\n" +
+		"5449,1058845404,CLIENTE UNO,107376,27/06/2023,\"75.000,00\",SUSUERTE S\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	run := Payment{Consecutive: 15001, FirstRC: 15001, CreateAt: "2023-06-27", FileName: "pagos.csv", Rows: 1}
+	if err := dr.SavePayment(ctx, run); err != nil {
+		t.Fatalf("Error al guardar el pago: %v", err)
+	}
+
+	mekano := NewMekanoRepository(dr, config.DefaultMappings(), Options{})
+	var invalid *InvalidAmountError
+	if _, err := mekano.RebuildPayment(path, run); !errors.As(err, &invalid) || len(invalid.Amounts) != 1 {
+		t.Errorf("Se esperaba un InvalidAmountError, se obtuvo: %v", err)
+	}
+}
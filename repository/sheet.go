@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/mozillazg/go-unidecode"
+)
+
+// Encabezados del archivo de pagos.
+const (
+	colPagoAbonado   = "N° Abonado"
+	colPagoDocumento = "Documento"
+	colPagoCliente   = "Cliente"
+	colPagoRecibo    = "Nro Recibo"
+	colPagoFecha     = "Fecha"
+	colPagoTotal     = "Total Pago"
+	colPagoCobrador  = "Cobrador"
+)
+
+// Encabezados del archivo de facturación.
+const (
+	colFacturaAbonado        = "Nro Abonado"
+	colFacturaIdentificacion = "Identificación"
+	colFacturaCliente        = "Cliente"
+	colFacturaConsecutivo    = "Consecutivo"
+	colFacturaFecha          = "Fecha Emisión"
+	colFacturaBase           = "Monto Base"
+	colFacturaIva            = "Monto IVA"
+	colFacturaTotal          = "Monto Total"
+	colFacturaCufe           = "Cufe"
+	colFacturaMunicipio      = "Municipio"
+	colFacturaItem           = "Item Facturado"
+)
+
+// Encabezados del archivo de extras.
+const (
+	colExtraAbonado = "ABONADO"
+	colExtraItem    = "ITEM"
+	colExtraBase    = "BASE"
+)
+
+// sheetSchema describe las columnas que se leen de un archivo de entrada.
+type sheetSchema struct {
+	name    string
+	columns []string
+}
+
+var paymentSchema = sheetSchema{
+	name:    "pagos",
+	columns: []string{colPagoAbonado, colPagoDocumento, colPagoCliente, colPagoRecibo, colPagoFecha, colPagoTotal, colPagoCobrador},
+}
+
+var billingSchema = sheetSchema{
+	name: "facturación",
+	columns: []string{
+		colFacturaAbonado, colFacturaIdentificacion, colFacturaCliente, colFacturaConsecutivo, colFacturaFecha,
+		colFacturaBase, colFacturaIva, colFacturaTotal, colFacturaCufe, colFacturaMunicipio, colFacturaItem,
+	},
+}
+
+var extrasSchema = sheetSchema{
+	name:    "extras",
+	columns: []string{colExtraAbonado, colExtraItem, colExtraBase},
+}
+
+// MissingColumnsError indica que un archivo no tiene todos los encabezados
+// que requiere su esquema.
+type MissingColumnsError struct {
+	Sheet   string
+	File    string
+	Columns []string
+}
+
+func (e *MissingColumnsError) Error() string {
+	return fmt.Sprintf("el archivo de %s %s no tiene las columnas: %s", e.Sheet, e.File, strings.Join(e.Columns, ", "))
+}
+
+// record es una fila de datos con acceso a sus celdas por encabezado.
+type record struct {
+	values  []string
+	columns map[string]int
+	line    int
+}
+
+// get devuelve el valor de la celda o una cadena vacía si la fila es más
+// corta que la columna, como ocurre cuando excelize omite celdas vacías al
+// final de la fila.
+func (r record) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.values) {
+		return ""
+	}
+	return r.values[i]
+}
+
+// missing devuelve las columnas que están vacías en la fila.
+func (r record) missing() []string {
+	var columns []string
+	for column := range r.columns {
+		if strings.TrimSpace(r.get(column)) == "" {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+func (r record) empty() bool {
+	for _, v := range r.values {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// sheetReader recorre las filas de datos de un archivo de entrada sin
+// cargarlo completo en memoria. Las columnas del esquema se resuelven a partir
+// de la fila de encabezados, comparándolos sin tildes, mayúsculas ni espacios
+// sobrantes. Las filas vacías se omiten y las filas con celdas faltantes se
+// informan y se procesan con esas celdas vacías.
+type sheetReader struct {
+	schema  sheetSchema
+	source  rowSource
+	columns map[string]int
+	line    int
+	record  record
+	err     error
+}
+
+// openSheet abre un archivo .xlsx o .csv y resuelve sus columnas.
+func openSheet(schema sheetSchema, path string, opts CSVOptions) (*sheetReader, error) {
+	source, err := openRows(path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := newSheetReader(schema, path, source)
+	if err != nil {
+		source.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func newSheetReader(schema sheetSchema, file string, source rowSource) (*sheetReader, error) {
+	if !source.Next() {
+		if err := source.Error(); err != nil {
+			return nil, err
+		}
+		return nil, &MissingColumnsError{Sheet: schema.name, File: file, Columns: schema.columns}
+	}
+
+	header, err := source.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := resolveColumns(schema, file, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sheetReader{schema: schema, source: source, columns: columns, line: 1}, nil
+}
+
+// Next avanza a la siguiente fila con datos.
+func (s *sheetReader) Next() bool {
+	for s.err == nil && s.source.Next() {
+		s.line++
+
+		values, err := s.source.Columns()
+		if err != nil {
+			s.err = err
+			return false
+		}
+
+		r := record{values: values, columns: s.columns, line: s.line}
+		if r.empty() {
+			continue
+		}
+		if missing := r.missing(); len(missing) > 0 {
+			log.Printf("Archivo de %s, fila %d incompleta, faltan: %s", s.schema.name, r.line, strings.Join(missing, ", "))
+		}
+
+		s.record = r
+		return true
+	}
+
+	if s.err == nil {
+		s.err = s.source.Error()
+	}
+	return false
+}
+
+// Record devuelve la fila actual.
+func (s *sheetReader) Record() record {
+	return s.record
+}
+
+// Err devuelve el error que detuvo la lectura, si lo hubo.
+func (s *sheetReader) Err() error {
+	return s.err
+}
+
+func (s *sheetReader) Close() error {
+	return s.source.Close()
+}
+
+func resolveColumns(schema sheetSchema, file string, header []string) (map[string]int, error) {
+	positions := map[string]int{}
+	for i, h := range header {
+		key := normalizeHeader(h)
+		if _, ok := positions[key]; !ok {
+			positions[key] = i
+		}
+	}
+
+	columns := map[string]int{}
+	var missing []string
+	for _, column := range schema.columns {
+		i, ok := positions[normalizeHeader(column)]
+		if !ok {
+			missing = append(missing, column)
+			continue
+		}
+		columns[column] = i
+	}
+
+	if len(missing) > 0 {
+		return nil, &MissingColumnsError{Sheet: schema.name, File: file, Columns: missing}
+	}
+	return columns, nil
+}
+
+func normalizeHeader(h string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(unidecode.Unidecode(h))), " ")
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// sliceRows es un rowSource en memoria para las pruebas.
+type sliceRows struct {
+	rows [][]string
+	i    int
+}
+
+func (s *sliceRows) Next() bool {
+	s.i++
+	return s.i <= len(s.rows)
+}
+
+func (s *sliceRows) Columns() ([]string, error) {
+	return s.rows[s.i-1], nil
+}
+
+func (s *sliceRows) Error() error {
+	return nil
+}
+
+func (s *sliceRows) Close() error {
+	return nil
+}
+
+// readSheet lee todas las filas de datos de rows.
+func readSheet(schema sheetSchema, file string, rows [][]string) ([]record, error) {
+	return readAll(newSheetReader(schema, file, &sliceRows{rows: rows}))
+}
+
+func readAll(s *sheetReader, err error) ([]record, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	var records []record
+	for s.Next() {
+		records = append(records, s.Record())
+	}
+	return records, s.Err()
+}
+
+func TestReadSheet(t *testing.T) {
+	rows := [][]string{
+		{"Cobrador", "N° Abonado", "documento", "Cliente", "Nro Recibo", "FECHA", "Columna Nueva", " Total  Pago "},
+		{"SUSUERTE S", "5449", "1060536367", "XIOMARA DURANGO GOEZ", "107376", "01/07/2023", "x", "75000"},
+	}
+
+	records, err := readSheet(paymentSchema, "pagos.xlsx", rows)
+	if err != nil {
+		t.Fatalf("Error al leer el archivo: %v", err)
+	}
+
+	got := []string{
+		records[0].get(colPagoDocumento),
+		records[0].get(colPagoCliente),
+		records[0].get(colPagoFecha),
+		records[0].get(colPagoTotal),
+		records[0].get(colPagoCobrador),
+	}
+	expected := []string{"1060536367", "XIOMARA DURANGO GOEZ", "01/07/2023", "75000", "SUSUERTE S"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Esperado: %v, obtenido: %v", expected, got)
+	}
+	if records[0].line != 2 {
+		t.Errorf("Se esperaba la fila 2, se obtuvo %d", records[0].line)
+	}
+}
+
+func TestReadSheetMissingColumns(t *testing.T) {
+	rows := [][]string{{"ABONADO", "DESCRIPCION", "BASE"}}
+
+	_, err := readSheet(extrasSchema, "extras.xlsx", rows)
+
+	var missing *MissingColumnsError
+	if !errors.As(err, &missing) {
+		t.Fatalf("Se esperaba un MissingColumnsError, se obtuvo: %v", err)
+	}
+	if !reflect.DeepEqual(missing.Columns, []string{colExtraItem}) {
+		t.Errorf("Columnas faltantes inesperadas: %v", missing.Columns)
+	}
+}
+
+func TestReadSheetRaggedRows(t *testing.T) {
+	rows := [][]string{
+		{"N° Abonado", "Documento", "Cliente", "Nro Recibo", "Fecha", "Total Pago", "Base", "IVA", "Estatus Pago", "Cobrador"},
+		{"5449", "1060536367", "XIOMARA DURANGO GOEZ", "107376", "01/07/2023", "75000"},
+		{},
+		{"5450", "1060536368"},
+	}
+
+	records, err := readSheet(paymentSchema, "pagos.xlsx", rows)
+	if err != nil {
+		t.Fatalf("Error al leer el archivo: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Se esperaban 2 filas, se obtuvieron %d", len(records))
+	}
+	if records[0].get(colPagoCobrador) != "" {
+		t.Errorf("Se esperaba un cobrador vacío, se obtuvo %q", records[0].get(colPagoCobrador))
+	}
+	if records[1].line != 4 {
+		t.Errorf("Se esperaba la fila 4, se obtuvo %d", records[1].line)
+	}
+
+	expected := []string{colPagoCliente, colPagoCobrador, colPagoFecha, colPagoRecibo, colPagoTotal}
+	if !reflect.DeepEqual(records[1].missing(), expected) {
+		t.Errorf("Columnas faltantes esperadas: %v, obtenidas: %v", expected, records[1].missing())
+	}
+}
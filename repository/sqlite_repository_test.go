@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+// newSQLiteTestRepository crea una base SQLite temporal con el esquema al día.
+func newSQLiteTestRepository(t *testing.T) DatabaseRepositoryInterface {
+	t.Helper()
+
+	repository, err := OpenDatabaseRepository("sqlite://" + filepath.Join(t.TempDir(), "mekano.db"))
+	if err != nil {
+		t.Fatalf("Error initializing the database: %v", err)
+	}
+
+	if _, err := repository.MigrateUp(context.Background(), 0); err != nil {
+		t.Fatalf("Error applying migrations: %v", err)
+	}
+	return repository
+}
+
+func TestSQLiteMigrations(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	status, err := repository.MigrationStatus(ctx)
+	if err != nil {
+		t.Fatalf("Error getting migration status: %v", err)
+	}
+	for _, s := range status {
+		if !s.Applied {
+			t.Errorf("Expected migration %d to be applied", s.Version)
+		}
+	}
+
+	reverted, err := repository.MigrateDown(ctx, len(status))
+	if err != nil {
+		t.Fatalf("Error reverting migrations: %v", err)
+	}
+	if len(reverted) != len(status) || reverted[0].Version != len(status) {
+		t.Errorf("Expected %d migrations reverted newest first, got %+v", len(status), reverted)
+	}
+
+	applied, err := repository.MigrateUp(ctx, 0)
+	if err != nil {
+		t.Fatalf("Error applying migrations again: %v", err)
+	}
+	if len(applied) != len(status) {
+		t.Errorf("Expected %d migrations applied, got %d", len(status), len(applied))
+	}
+}
+
+func TestSQLitePayments(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	if _, err := repository.GetPayment(ctx); !errors.Is(err, ErrNoPaymentHistory) {
+		t.Fatalf("Expected ErrNoPaymentHistory, got: %v", err)
+	}
+
+	payment := Payment{
+		Consecutive: 15010,
+		CreateAt:    "2023-01-13",
+		FileName:    "payment_test.xlsx",
+		FirstRC:     15001,
+		Rows:        10,
+		Bancolombia: 100000,
+		Davivienda:  50000,
+		Susuerte:    75000,
+		PayU:        25000,
+		Cash:        30000,
+		Total:       280000,
+	}
+	if err := repository.SavePayment(ctx, payment); err != nil {
+		t.Fatalf("Error saving payment: %v", err)
+	}
+
+	stored, err := repository.GetPayment(ctx)
+	if err != nil {
+		t.Fatalf("Error getting payment: %v", err)
+	}
+	if !reflect.DeepEqual(stored, payment) {
+		t.Errorf("Expected data: %+v, got: %+v", payment, stored)
+	}
+
+	// Sin fila en mekanosequences se continúa desde el último pago.
+	consecutive, err := repository.GetConsecutive(ctx, SequenceRC)
+	if err != nil {
+		t.Fatalf("Error getting consecutive: %v", err)
+	}
+	if consecutive != 15010 {
+		t.Errorf("Expected consecutive 15010, got: %d", consecutive)
+	}
+}
+
+func TestSQLiteSaveBilling(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+
+	billing := Billing{
+		Debit:    100,
+		Credit:   200,
+		Base:     300,
+		FileName: "billing_test.xlsx",
+		CreateAt: "2023-01-13",
+	}
+
+	if err := repository.SaveBilling(context.Background(), billing); err != nil {
+		t.Fatalf("Error saving billing: %v", err)
+	}
+}
+
+func TestSQLiteMappings(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	mappings := config.Mappings{
+		Accounts: map[string]string{"PLAN SENIOR SIMETRICO": "41457057"},
+	}
+	if err := repository.SaveMappings(ctx, mappings); err != nil {
+		t.Fatalf("Error saving mappings: %v", err)
+	}
+
+	// Guardar de nuevo el mismo nombre actualiza el valor.
+	mappings.Accounts["PLAN SENIOR SIMETRICO"] = "41457058"
+	if err := repository.SaveMappings(ctx, mappings); err != nil {
+		t.Fatalf("Error updating mappings: %v", err)
+	}
+
+	stored, err := repository.GetMappings(ctx)
+	if err != nil {
+		t.Fatalf("Error getting mappings: %v", err)
+	}
+	if stored.Accounts["PLAN SENIOR SIMETRICO"] != "41457058" {
+		t.Errorf("Expected account 41457058, got: %q", stored.Accounts["PLAN SENIOR SIMETRICO"])
+	}
+
+	if err := repository.DeleteMapping(ctx, config.SectionAccounts, "PLAN SENIOR SIMETRICO"); err != nil {
+		t.Fatalf("Error deleting mapping: %v", err)
+	}
+	if err := repository.DeleteMapping(ctx, config.SectionAccounts, "PLAN SENIOR SIMETRICO"); err == nil {
+		t.Error("Expected an error deleting a missing mapping")
+	}
+}
+
+func TestSQLiteReceiptsAndInvoices(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	receipt := Receipt{
+		Number:      "889201",
+		Subscriber:  "5449",
+		Amount:      "75000",
+		PaymentDate: "01/07/2023",
+		Consecutive: 15011,
+		CreateAt:    "2023-01-13",
+	}
+	if err := repository.SaveReceipts(ctx, []Receipt{receipt}); err != nil {
+		t.Fatalf("Error saving receipts: %v", err)
+	}
+
+	receipts, err := repository.GetReceipts(ctx, []string{receipt.Number, "no-existe"})
+	if err != nil {
+		t.Fatalf("Error getting receipts: %v", err)
+	}
+	if expected := map[string]Receipt{receipt.Number: receipt}; !reflect.DeepEqual(receipts, expected) {
+		t.Errorf("Expected data: %+v, got: %+v", expected, receipts)
+	}
+
+	invoice := Invoice{
+		Cufe:       "3f7a9c",
+		Number:     "66137",
+		Subscriber: "3296",
+		FileName:   "billing_test.xlsx",
+		CreateAt:   "2023-01-13",
+	}
+	if err := repository.SaveInvoices(ctx, []Invoice{invoice}); err != nil {
+		t.Fatalf("Error saving invoices: %v", err)
+	}
+
+	invoices, err := repository.GetInvoices(ctx, []string{invoice.Cufe})
+	if err != nil {
+		t.Fatalf("Error getting invoices: %v", err)
+	}
+	if expected := map[string]Invoice{invoice.Cufe: invoice}; !reflect.DeepEqual(invoices, expected) {
+		t.Errorf("Expected data: %+v, got: %+v", expected, invoices)
+	}
+}
+
+func TestSQLiteSaveReceiptsInChunks(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	var receipts []Receipt
+	var numbers []string
+	for i := 0; i < 2*inQueryChunk+1; i++ {
+		number := strconv.Itoa(100000 + i)
+		receipts = append(receipts, Receipt{Number: number, Subscriber: "5449", Amount: "75000.00", PaymentDate: "01/07/2023", Consecutive: 15001 + i, CreateAt: "2023-01-13"})
+		numbers = append(numbers, number)
+	}
+	if err := repository.SaveReceipts(ctx, receipts); err != nil {
+		t.Fatalf("Error saving receipts: %v", err)
+	}
+
+	saved, err := repository.GetReceipts(ctx, numbers)
+	if err != nil {
+		t.Fatalf("Error getting receipts: %v", err)
+	}
+	if len(saved) != len(receipts) || saved["101000"].Consecutive != 16001 {
+		t.Errorf("Expected %d receipts, got %d", len(receipts), len(saved))
+	}
+}
+
+func TestSQLiteSaveInvoicesInChunks(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	var invoices []Invoice
+	var cufes []string
+	for i := 0; i < 2*inQueryChunk+1; i++ {
+		cufe := fmt.Sprintf("cufe%d", i)
+		invoices = append(invoices, Invoice{Cufe: cufe, Number: strconv.Itoa(60000 + i), Subscriber: "3296", FileName: "billing.xlsx", CreateAt: "2023-01-13"})
+		cufes = append(cufes, cufe)
+	}
+	if err := repository.SaveInvoices(ctx, invoices); err != nil {
+		t.Fatalf("Error saving invoices: %v", err)
+	}
+
+	saved, err := repository.GetInvoices(ctx, cufes)
+	if err != nil {
+		t.Fatalf("Error getting invoices: %v", err)
+	}
+	if len(saved) != len(invoices) || saved["cufe1000"].Number != "61000" {
+		t.Errorf("Expected %d invoices, got %d", len(invoices), len(saved))
+	}
+}
+
+func TestSQLiteReserveConsecutives(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	if _, err := repository.ReserveConsecutives(ctx, SequenceRC, 1); !errors.Is(err, ErrNoPaymentHistory) {
+		t.Fatalf("Expected ErrNoPaymentHistory, got: %v", err)
+	}
+
+	if err := repository.SeedConsecutive(ctx, SequenceRC, 15000); err != nil {
+		t.Fatalf("Error seeding consecutive: %v", err)
+	}
+	if err := repository.SeedConsecutive(ctx, SequenceRC, 20000); err == nil {
+		t.Error("Expected an error seeding an initialized sequence")
+	}
+
+	const workers, size = 5, 10
+	firsts := make(chan int, workers)
+	errs := make(chan error, workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservation, err := repository.ReserveConsecutives(ctx, SequenceRC, size)
+			if err == nil {
+				err = reservation.Commit()
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+			firsts <- reservation.First
+		}()
+	}
+	wg.Wait()
+	close(firsts)
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Error reserving consecutives: %v", err)
+	}
+
+	var reserved []int
+	for first := range firsts {
+		reserved = append(reserved, first)
+	}
+	sort.Ints(reserved)
+
+	expected := []int{15000, 15010, 15020, 15030, 15040}
+	if !reflect.DeepEqual(reserved, expected) {
+		t.Errorf("Expected ranges starting at %v, got: %v", expected, reserved)
+	}
+}
+
+func TestSQLiteReservationRollback(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	if err := repository.SeedConsecutive(ctx, SequenceRC, 15000); err != nil {
+		t.Fatalf("Error seeding consecutive: %v", err)
+	}
+	reservation, err := repository.ReserveConsecutives(ctx, SequenceRC, 5)
+	if err != nil {
+		t.Fatalf("Error reserving consecutives: %v", err)
+	}
+	if err := reservation.Rollback(); err != nil {
+		t.Fatalf("Error rolling back reservation: %v", err)
+	}
+	if err := reservation.Commit(); err != nil {
+		t.Errorf("Commit after rollback should be a no-op, got: %v", err)
+	}
+
+	consecutive, err := repository.GetConsecutive(ctx, SequenceRC)
+	if err != nil || consecutive != 14999 {
+		t.Errorf("Expected the sequence unchanged at 14999, got: %d, %v", consecutive, err)
+	}
+}
+
+func TestOpenDatabaseRepositoryUnknownScheme(t *testing.T) {
+	if _, err := OpenDatabaseRepository("postgres://localhost/mekano"); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+}
+
+func TestSQLiteBatches(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	lines := []MekanoDataStruct{
+		{Tipo: "RC", Prefijo: "_", Numero: "15001", Secuencia: "1", Fecha: "01/07/2023", Cuenta: "13050501", Terceros: "1060588", Nota: "RECAUDO POR VENTA SERVICIOS", Debito: "0", Credito: "75000", Interface: "13/01/2023 10:00"},
+		{Tipo: "RC", Prefijo: "_", Numero: "15001", Secuencia: "2", Fecha: "01/07/2023", Cuenta: "13452505", Terceros: "1060588", Nota: "RECAUDO, CON COMA", Debito: "75000", Credito: "0", Interface: "13/01/2023 10:00"},
+	}
+	batch := Batch{
+		Kind:     BatchPayment,
+		FileName: "payment_test.xlsx",
+		Checksum: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		Operator: "caja1",
+		CreateAt: "2023-01-13 10:00:00",
+		Output:   &OutputOptions{Encoding: "windows-1252", CRLF: true},
+		Lines:    lines,
+	}
+
+	id, err := repository.SaveBatch(ctx, batch)
+	if err != nil {
+		t.Fatalf("Error saving batch: %v", err)
+	}
+
+	stored, err := repository.GetBatch(ctx, id)
+	if err != nil {
+		t.Fatalf("Error getting batch: %v", err)
+	}
+	batch.ID = id
+	if !reflect.DeepEqual(stored, batch) {
+		t.Errorf("Expected data: %+v, got: %+v", batch, stored)
+	}
+
+	// El archivo regenerado debe ser idéntico al original.
+	dir := t.TempDir()
+	original, reexported := filepath.Join(dir, "original.txt"), filepath.Join(dir, "reexported.txt")
+	if err := writeInterfaceFile(original, lines, *batch.Output); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteBatch(reexported, stored, *stored.Output); err != nil {
+		t.Fatalf("Error writing batch: %v", err)
+	}
+	a, _ := os.ReadFile(original)
+	b, _ := os.ReadFile(reexported)
+	if string(a) != string(b) {
+		t.Errorf("Expected identical interface files:\n%s\n%s", a, b)
+	}
+
+	if _, err := repository.GetBatch(ctx, id+1); !errors.Is(err, ErrBatchNotFound) {
+		t.Errorf("Expected ErrBatchNotFound, got: %v", err)
+	}
+}
+
+func TestSQLiteBatchInChunks(t *testing.T) {
+	repository := newSQLiteTestRepository(t)
+	ctx := context.Background()
+
+	var lines []MekanoDataStruct
+	for i := 0; i < 2*inQueryChunk+1; i++ {
+		lines = append(lines, MekanoDataStruct{Tipo: "FVE", Prefijo: "_", Numero: strconv.Itoa(60000 + i), Debito: "75000.00", Credito: "0.00"})
+	}
+
+	id, err := repository.SaveBatch(ctx, Batch{Kind: BatchBilling, FileName: "billing.xlsx", CreateAt: "2023-01-13 10:00:00", Lines: lines})
+	if err != nil {
+		t.Fatalf("Error saving batch: %v", err)
+	}
+
+	stored, err := repository.GetBatch(ctx, id)
+	if err != nil {
+		t.Fatalf("Error getting batch: %v", err)
+	}
+	if !reflect.DeepEqual(stored.Lines, lines) {
+		t.Errorf("Expected %d lines in order, got %d", len(lines), len(stored.Lines))
+	}
+	if stored.Output != nil {
+		t.Errorf("Expected no output format for a batch saved without it, got: %+v", stored.Output)
+	}
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mozillazg/go-unidecode"
+)
+
+// maxSuggestions es la cantidad de mapeos similares que se sugieren para un
+// ítem sin cuenta.
+const maxSuggestions = 3
+
+// Suggestion es un mapeo existente parecido a un valor sin mapeo.
+type Suggestion struct {
+	Name     string
+	Code     string
+	Distance int
+}
+
+func (s Suggestion) String() string {
+	return fmt.Sprintf("%q (%s)", s.Name, s.Code)
+}
+
+// suggest devuelve los nombres de values más cercanos a key según la
+// distancia de edición entre sus formas normalizadas. Se descartan los
+// candidatos que difieren en más de la mitad de sus caracteres.
+func suggest(key string, values map[string]string, limit int) []Suggestion {
+	normalizedKey := normalizeName(key)
+
+	var suggestions []Suggestion
+	for name, code := range values {
+		normalizedName := normalizeName(name)
+		distance := levenshtein(normalizedKey, normalizedName)
+
+		longest := len(normalizedKey)
+		if len(normalizedName) > longest {
+			longest = len(normalizedName)
+		}
+		if distance*2 > longest {
+			continue
+		}
+
+		suggestions = append(suggestions, Suggestion{Name: name, Code: code, Distance: distance})
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Distance != suggestions[j].Distance {
+			return suggestions[i].Distance < suggestions[j].Distance
+		}
+		return suggestions[i].Name < suggestions[j].Name
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// normalizeName quita tildes, mayúsculas, puntuación y espacios repetidos
+// para comparar nombres de planes.
+func normalizeName(name string) string {
+	name = strings.ToUpper(unidecode.Unidecode(name))
+	name = strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// levenshtein calcula la distancia de edición entre dos cadenas ASCII.
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/OzkrOssa/mekano-cli/config"
+)
+
+func TestSuggest(t *testing.T) {
+	suggestions := suggest("PLAN BASICO HOGAR 2 ESPECIAL", config.Accounts, maxSuggestions)
+	if len(suggestions) == 0 {
+		t.Fatalf("Se esperaban sugerencias")
+	}
+	if suggestions[0].Name != "PLAN BASICO HOGAR 2 (ESPECIAL)" {
+		t.Errorf("Sugerencia inesperada: %+v", suggestions)
+	}
+
+	suggestions = suggest("comercial básico rural", config.Accounts, maxSuggestions)
+	if len(suggestions) == 0 || normalizeName(suggestions[0].Name) != "COMERCIAL BASICO RURAL" {
+		t.Errorf("Sugerencia inesperada: %+v", suggestions)
+	}
+
+	if suggestions := suggest("XYZ", config.Accounts, maxSuggestions); len(suggestions) != 0 {
+		t.Errorf("No se esperaban sugerencias: %+v", suggestions)
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"HOGAR", "HOGAR", 0},
+		{"HOGAR", "HOGARES", 2},
+		{"KITTEN", "SITTING", 3},
+	}
+	for _, c := range cases {
+		if got := levenshtein(c.a, c.b); got != c.want {
+			t.Errorf("levenshtein(%q, %q) = %d, se esperaba %d", c.a, c.b, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,301 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// ErrSyncConflicts indica que sync encontró conflictos y no envió nada.
+var ErrSyncConflicts = errors.New("el diario tiene conflictos con la base central: revíselos y ejecute sync -force para enviar el resto")
+
+// SyncConflict es un cambio del diario que choca con la base central.
+type SyncConflict struct {
+	Kind   string
+	Key    string
+	Detail string
+}
+
+// SyncReport resume lo enviado a la base central.
+type SyncReport struct {
+	Payments int
+	Billings int
+	Receipts int
+	Invoices int
+	// Batches relaciona el id local de cada lote con el asignado en la base
+	// central. Los pagos y facturaciones se envían con el id central.
+	Batches   map[int64]int64
+	Conflicts []SyncConflict
+	// Sequences es el último consecutivo de cada secuencia en la base
+	// central después de sincronizar.
+	Sequences map[string]int
+	// Archive es la copia del diario sincronizado.
+	Archive string
+}
+
+// reservedRange agrupa los consecutivos reservados sin conexión de una
+// secuencia.
+type reservedRange struct {
+	first, last int
+}
+
+// Sync envía a la base central los cambios del diario. Antes de escribir
+// detecta conflictos: consecutivos que la base central ya asignó y recibos o
+// facturas que ya fueron registrados allí. Si hay conflictos y force es
+// falso no envía nada; con force omite los recibos y facturas repetidos y
+// avanza la secuencia central hasta el último consecutivo del diario.
+//
+// Al terminar, el diario se archiva y se reemplaza por el último consecutivo
+// de la base central, para seguir trabajando sin conexión desde allí.
+func (j *JournalRepository) Sync(ctx context.Context, target DatabaseRepositoryInterface, force bool) (SyncReport, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := SyncReport{Batches: map[int64]int64{}, Sequences: map[string]int{}}
+	entries := j.entries
+
+	ranges := map[string]reservedRange{}
+	var numbers, cufes []string
+	for _, entry := range entries {
+		switch entry.Kind {
+		case journalReserve:
+			r, ok := ranges[entry.Sequence]
+			last := entry.First + entry.Count - 1
+			if !ok || entry.First < r.first {
+				r.first = entry.First
+			}
+			if last > r.last {
+				r.last = last
+			}
+			ranges[entry.Sequence] = r
+		case journalReceipts:
+			for _, receipt := range entry.Receipts {
+				numbers = append(numbers, receipt.Number)
+			}
+		case journalInvoices:
+			for _, invoice := range entry.Invoices {
+				cufes = append(cufes, invoice.Cufe)
+			}
+		}
+	}
+
+	sequences := make([]string, 0, len(ranges))
+	for sequence := range ranges {
+		sequences = append(sequences, sequence)
+	}
+	sort.Strings(sequences)
+
+	central := map[string]int{}
+	unseeded := map[string]bool{}
+	for _, sequence := range sequences {
+		r := ranges[sequence]
+		value, err := target.GetConsecutive(ctx, sequence)
+		if errors.Is(err, ErrNoPaymentHistory) {
+			// La base central nunca asignó consecutivos: se inicializa con
+			// el primero que se asignó sin conexión.
+			value, err = r.first-1, nil
+			unseeded[sequence] = true
+		}
+		if err != nil {
+			return report, err
+		}
+		central[sequence] = value
+
+		if value >= r.first {
+			report.Conflicts = append(report.Conflicts, SyncConflict{
+				Kind:   journalReserve,
+				Key:    fmt.Sprintf("%s %d-%d", sequence, r.first, r.last),
+				Detail: fmt.Sprintf("la base central ya asignó hasta el %d", value),
+			})
+		}
+	}
+
+	existingReceipts, err := target.GetReceipts(ctx, numbers)
+	if err != nil {
+		return report, err
+	}
+	for _, number := range numbers {
+		if receipt, ok := existingReceipts[number]; ok {
+			report.Conflicts = append(report.Conflicts, SyncConflict{
+				Kind:   journalReceipts,
+				Key:    number,
+				Detail: fmt.Sprintf("ya fue importado con el RC %d el %s", receipt.Consecutive, receipt.CreateAt),
+			})
+		}
+	}
+
+	existingInvoices, err := target.GetInvoices(ctx, cufes)
+	if err != nil {
+		return report, err
+	}
+	for _, cufe := range cufes {
+		if invoice, ok := existingInvoices[cufe]; ok {
+			report.Conflicts = append(report.Conflicts, SyncConflict{
+				Kind:   journalInvoices,
+				Key:    cufe,
+				Detail: fmt.Sprintf("la factura %s ya fue exportada en %s el %s", invoice.Number, invoice.FileName, invoice.CreateAt),
+			})
+		}
+	}
+
+	if len(report.Conflicts) > 0 && !force {
+		return report, ErrSyncConflicts
+	}
+
+	var pending []journalEntry
+	for _, sequence := range sequences {
+		r, value := ranges[sequence], central[sequence]
+		if value < r.last {
+			if unseeded[sequence] {
+				if err := target.SeedConsecutive(ctx, sequence, r.first); err != nil {
+					return report, err
+				}
+			}
+			reservation, err := target.ReserveConsecutives(ctx, sequence, r.last-value)
+			if err != nil {
+				return report, err
+			}
+			if reservation.First != value+1 {
+				reservation.Rollback()
+				return report, fmt.Errorf("la secuencia %s cambió en la base central durante la sincronización, vuelva a ejecutar sync", sequence)
+			}
+			if err := reservation.Commit(); err != nil {
+				return report, err
+			}
+			value = r.last
+		}
+		pending = append(pending, journalEntry{
+			Kind:     journalCheckpoint,
+			At:       time.Now().Format("2006-01-02 15:04:05"),
+			Sequence: sequence,
+			Value:    value,
+		})
+	}
+
+	// Las entradas se envían en orden. Si una falla, el diario conserva las
+	// pendientes para el siguiente intento, junto con los consecutivos ya
+	// reservados en la base central y los lotes ya enviados con su id
+	// central.
+	for i, entry := range entries {
+		var err error
+		switch entry.Kind {
+		case journalPayment:
+			payment := *entry.Payment
+			payment.BatchID = report.Batches[payment.BatchID]
+			if err = target.SavePayment(ctx, payment); err == nil {
+				report.Payments++
+			}
+		case journalBilling:
+			billing := *entry.Billing
+			billing.BatchID = report.Batches[billing.BatchID]
+			if err = target.SaveBilling(ctx, billing); err == nil {
+				report.Billings++
+			}
+		case journalReceipts:
+			var receipts []Receipt
+			for _, receipt := range entry.Receipts {
+				if _, ok := existingReceipts[receipt.Number]; !ok {
+					receipts = append(receipts, receipt)
+				}
+			}
+			if len(receipts) > 0 {
+				if err = target.SaveReceipts(ctx, receipts); err == nil {
+					report.Receipts += len(receipts)
+				}
+			}
+		case journalInvoices:
+			var invoices []Invoice
+			for _, invoice := range entry.Invoices {
+				if _, ok := existingInvoices[invoice.Cufe]; !ok {
+					invoices = append(invoices, invoice)
+				}
+			}
+			if len(invoices) > 0 {
+				if err = target.SaveInvoices(ctx, invoices); err == nil {
+					report.Invoices += len(invoices)
+				}
+			}
+		case journalBatch:
+			if entry.CentralID != 0 {
+				report.Batches[entry.Batch.ID] = entry.CentralID
+				break
+			}
+			var id int64
+			if id, err = target.SaveBatch(ctx, *entry.Batch); err == nil {
+				report.Batches[entry.Batch.ID] = id
+			}
+		}
+		if err != nil {
+			pending = append(pending, sentBatches(entries[:i], report.Batches)...)
+			if rerr := j.rewrite(append(pending, pendingEntries(entries[i:])...)); rerr != nil {
+				return report, fmt.Errorf("%v; además no se pudo actualizar el diario: %w", err, rerr)
+			}
+			return report, err
+		}
+	}
+
+	archive := fmt.Sprintf("%s.%s.synced", j.path, time.Now().Format("20060102-150405"))
+	if len(pendingEntries(entries)) > 0 {
+		if err := copyFile(j.path, archive); err != nil {
+			return report, err
+		}
+		report.Archive = archive
+	}
+
+	// El diario queda solo con los consecutivos actuales de la base central.
+	var checkpoints []journalEntry
+	for _, sequence := range []string{SequenceRC} {
+		value, err := target.GetConsecutive(ctx, sequence)
+		if errors.Is(err, ErrNoPaymentHistory) {
+			continue
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Sequences[sequence] = value
+		checkpoints = append(checkpoints, journalEntry{
+			Kind:     journalCheckpoint,
+			At:       time.Now().Format("2006-01-02 15:04:05"),
+			Sequence: sequence,
+			Value:    value,
+		})
+	}
+	return report, j.rewrite(checkpoints)
+}
+
+// pendingEntries quita los consecutivos del diario, que ya se enviaron antes
+// que las demás entradas.
+func pendingEntries(entries []journalEntry) []journalEntry {
+	var pending []journalEntry
+	for _, entry := range entries {
+		if entry.Kind != journalReserve && entry.Kind != journalCheckpoint {
+			pending = append(pending, entry)
+		}
+	}
+	return pending
+}
+
+// sentBatches devuelve los lotes ya enviados con su id central, para que el
+// siguiente intento no los repita y envíe con ese id los pagos y
+// facturaciones que los referencian.
+func sentBatches(entries []journalEntry, central map[int64]int64) []journalEntry {
+	var sent []journalEntry
+	for _, entry := range entries {
+		if entry.Kind == journalBatch {
+			entry.CentralID = central[entry.Batch.ID]
+			sent = append(sent, entry)
+		}
+	}
+	return sent
+}
+
+func copyFile(src, dst string) error {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, content, 0o644)
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxReportedRows limita cuántas filas de ejemplo se muestran por valor sin
+// mapeo.
+const maxReportedRows = 5
+
+// UnmappedKey es un plan, caja o municipio que no tiene equivalencia en los
+// mapeos, con la cantidad de apariciones y las primeras filas donde aparece.
+type UnmappedKey struct {
+	Section     string
+	Key         string
+	Count       int
+	Rows        []int
+	Suggestions []Suggestion
+}
+
+func (k UnmappedKey) String() string {
+	rows := make([]string, len(k.Rows))
+	for i, r := range k.Rows {
+		rows[i] = strconv.Itoa(r)
+	}
+	line := fmt.Sprintf("%s %q: %d veces, filas %s", k.Section, k.Key, k.Count, strings.Join(rows, ", "))
+
+	if len(k.Suggestions) > 0 {
+		suggestions := make([]string, len(k.Suggestions))
+		for i, s := range k.Suggestions {
+			suggestions[i] = s.String()
+		}
+		line += "; quizás: " + strings.Join(suggestions, ", ")
+	}
+	return line
+}
+
+// UnmappedError se devuelve en modo estricto cuando el archivo contiene
+// valores sin mapeo.
+type UnmappedError struct {
+	Keys []UnmappedKey
+}
+
+func (e *UnmappedError) Error() string {
+	return fmt.Sprintf("%d valores sin mapeo, no se exportó la interfaz:\n%s", len(e.Keys), unmappedReport(e.Keys))
+}
+
+func unmappedReport(keys []UnmappedKey) string {
+	lines := make([]string, len(keys))
+	for i, k := range keys {
+		lines[i] = "  " + k.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unmappedTracker acumula los valores sin mapeo encontrados durante el
+// procesamiento de un archivo.
+type unmappedTracker struct {
+	keys  []UnmappedKey
+	index map[string]int
+}
+
+func newUnmappedTracker() *unmappedTracker {
+	return &unmappedTracker{index: map[string]int{}}
+}
+
+// lookup busca key en values y registra la fila si no existe.
+func (t *unmappedTracker) lookup(section string, values map[string]string, key string, row int) string {
+	value, ok := values[key]
+	if !ok {
+		t.add(section, key, row)
+	}
+	return value
+}
+
+func (t *unmappedTracker) add(section, key string, row int) {
+	id := section + "\x00" + key
+	i, ok := t.index[id]
+	if !ok {
+		i = len(t.keys)
+		t.index[id] = i
+		t.keys = append(t.keys, UnmappedKey{Section: section, Key: key})
+	}
+
+	t.keys[i].Count++
+	if len(t.keys[i].Rows) < maxReportedRows {
+		t.keys[i].Rows = append(t.keys[i].Rows, row)
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/OzkrOssa/mekano-cli/repository"
+)
+
+// runSync atiende el subcomando sync, que envía a la base de datos central
+// los consecutivos, pagos, recibos y facturas registrados sin conexión.
+func runSync(argv []string) {
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	force := fs.Bool("force", false, "Envía los cambios sin conflicto y omite los recibos y facturas ya registrados")
+	var dsn string
+	dbFlag(fs, &dsn)
+	journalFile := fs.String("journal", journalPath(), "Ruta del diario sin conexión (por defecto MEKANO_JOURNAL)")
+	fs.Parse(argv)
+
+	d, err := openDatabase(dsn)
+	if err != nil {
+		log.Fatalf("no se pudo conectar a la base de datos: %v", err)
+	}
+
+	journal, err := repository.NewJournalRepository(*journalFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	report, err := journal.Sync(ctx, d, *force)
+	for _, c := range report.Conflicts {
+		fmt.Printf("Conflicto %s %s: %s\n", c.Kind, c.Key, c.Detail)
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrSyncConflicts) {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("Sincronizados: %d pagos, %d facturaciones, %d recibos, %d facturas\n", report.Payments, report.Billings, report.Receipts, report.Invoices)
+	if report.Archive != "" {
+		fmt.Printf("Diario archivado en %s\n", report.Archive)
+	}
+	if rc, ok := report.Sequences[repository.SequenceRC]; ok {
+		fmt.Printf("Último RC en la base central: %d\n", rc)
+	}
+}